@@ -1,34 +1,274 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"github.com/RichardKnop/arbitrage/bittrex"
 	"github.com/RichardKnop/arbitrage/bot"
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/journal"
+	"github.com/RichardKnop/arbitrage/metrics"
+	"github.com/RichardKnop/arbitrage/paper"
+	"github.com/RichardKnop/arbitrage/poloniex"
+	"github.com/RichardKnop/arbitrage/recorder"
+	"github.com/RichardKnop/arbitrage/replay"
+	"github.com/RichardKnop/arbitrage/storage"
+	"github.com/RichardKnop/arbitrage/symbols"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// ErrLiveAndPaper is returned when both live and paper trading are requested
+var ErrLiveAndPaper = errors.New("Live and paper trading cannot be combined")
+
+var (
+	live          = flag.Bool("live", false, "place real orders with BITTREX_API_KEY, opportunities are only logged without it or -paper")
+	paperBalances = flag.String("paper", "", "JSON file with virtual balances per exchange, enables paper trading")
+	symbolsConfig = flag.String("symbols", "", "JSON file with symbol formats and aliases extending the defaults")
+	feesConfig    = flag.String("fees", "", "JSON file with fee overrides merged into the default schedules")
+	journalPath   = flag.String("journal", "orders.journal", "file recording every order sent, reconciled on startup")
+	metricsAddr   = flag.String("metrics", "", "address /metrics is served on for Prometheus, e.g. :8090, disabled when empty")
+	dbDir         = flag.String("db", "arbitrage.db", "directory of the database keeping trading history")
+	historyAddr   = flag.String("history", "", "address trading history is served on as JSON, e.g. :8091, disabled when empty")
+	recordDir     = flag.String("record", "", "directory market data is recorded to, disabled when empty")
+	recordFormat  = flag.String("record-format", string(recorder.JSONLines), "format of recorded market data, jsonl or csv")
+	replayFiles   = flag.String("replay", "", "glob of recorded files replayed instead of live market data")
+	replaySpeed   = flag.Float64("replay-speed", 1, "replay speed factor, zero replays as fast as possible")
+	verbose       = flag.Bool("verbose", false, "log every ticker received")
 )
 
 func main() {
+	// Backtests run detectors over recorded data instead of live exchanges
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		if err := runBacktest(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	flag.Parse()
+
+	if *live && *paperBalances != "" {
+		log.Fatal(ErrLiveAndPaper)
+	}
+
+	// Maps exchange specific market names to canonical pairs
+	registry, err := newRegistry(*symbolsConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Exchanges
+	bittrexExchange := bittrex.New(&bittrex.Config{
+		Host:              bittrex.APIHost,
+		BatchSize:         bittrex.DefaultBatchSize,
+		BatchInterval:     bittrex.DefaultBatchInterval,
+		PollingMode:       bittrex.SummariesPolling,
+		SummariesInterval: bittrex.DefaultSummariesInterval,
+		OrderBookMarkets:  []string{"BTC-ETH", "BTC-LTC"},
+		OrderBookDepth:    bittrex.DefaultOrderBookDepth,
+		OrderBookInterval: bittrex.DefaultOrderBookInterval,
+		Symbols:           registry,
+		APIKey:            os.Getenv("BITTREX_API_KEY"),
+		APISecret:         os.Getenv("BITTREX_API_SECRET"),
+	})
+	poloniexExchange := poloniex.New(&poloniex.Config{
+		Host:         poloniex.APIHost,
+		PollInterval: poloniex.DefaultPollInterval,
+		Symbols:      registry,
+	})
+
+	// Trading fees used in profitability calculations, withdrawal fees are fetched from exchanges
+	feeModel, err := newFeeModel(*feesConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Replay feeds recorded market data under names of the live exchanges
+	exchanges := []types.Exchange{bittrexExchange, poloniexExchange}
+	var (
+		sequencer    *replay.Sequencer
+		bittrexPairs []types.Pair
+	)
+	if *replayFiles != "" {
+		files, err := filepath.Glob(*replayFiles)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sequencer = replay.NewSequencer(&replay.Config{
+			Files:      files,
+			Speed:      *replaySpeed,
+			OrderBooks: true,
+		})
+		for i, e := range exchanges {
+			exchanges[i] = sequencer.Exchange(e.GetName())
+		}
+
+		// Replay must not depend on the exchange being reachable, the recording lists its markets
+		bittrexPairs, err = recordedMarkets(files, bittrexExchange.GetName())
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		// Bittrex markets are needed to build the triangular arbitrage graph
+		bittrexPairs, err = activeMarkets(bittrexExchange, registry)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Paper trading wraps each exchange to simulate orders against live prices
+	if *paperBalances != "" {
+		exchanges, err = paperExchanges(*paperBalances, feeModel, exchanges)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Orders left open by a previous run are reconciled on startup
+	orderJournal, err := journal.Open(*journalPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer orderJournal.Close()
+
+	// Trading history survives restarts and upgrades
+	db, err := storage.Open(*dbDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Trading history is read by reporting tools
+	if *historyAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(*historyAddr, db.Handler()); err != nil {
+				log.Printf("History server error: %v", err)
+			}
+		}()
+	}
+
+	// Market data is recorded for research and reproducing incidents
+	var tickRecorder *recorder.Recorder
+	if *recordDir != "" {
+		tickRecorder = recorder.New(&recorder.Config{
+			Dir:            *recordDir,
+			Format:         recorder.Format(*recordFormat),
+			MaxSize:        recorder.DefaultMaxSize,
+			RotateInterval: recorder.DefaultRotateInterval,
+			FlushInterval:  recorder.DefaultFlushInterval,
+		})
+		defer tickRecorder.Close()
+	}
+
+	// Real orders are only placed when asked for explicitly, paper trade first
+	var execution *bot.ExecutorConfig
+	if *live || *paperBalances != "" {
+		execution = &bot.ExecutorConfig{
+			FillTimeout:    bot.DefaultFillTimeout,
+			PollInterval:   bot.DefaultOrderPollInterval,
+			CancelTimeout:  bot.DefaultCancelTimeout,
+			Policy:         bot.UnwindResidual,
+			MaxSlippageBps: bot.DefaultMaxSlippageBps,
+		}
+	}
+
 	// Run the bot
-	b := bot.New(bittrex.New(&bittrex.Config{
-		Host:          bittrex.APIHost,
-		BatchSize:     bittrex.DefaultBatchSize,
-		BatchInterval: bittrex.DefaultBatchInterval,
-	}))
+	b := bot.New(&bot.Config{
+		Fees:            feeModel,
+		Journal:         orderJournal,
+		Recorder:        tickRecorder,
+		Storage:         db,
+		BalanceInterval: bot.DefaultBalanceInterval,
+		RulesInterval:   bot.DefaultRulesInterval,
+		Verbose:         *verbose,
+		Spread: &bot.SpreadConfig{
+			MinNetSpreadBps: bot.DefaultMinNetSpreadBps,
+			MaxQuoteAge:     bot.DefaultMaxQuoteAge,
+		},
+		Triangular: &bot.TriangularConfig{
+			Exchange:     bittrexExchange.GetName(),
+			Markets:      bittrexPairs,
+			MinReturnBps: bot.DefaultMinReturnBps,
+			MaxQuoteAge:  bot.DefaultMaxQuoteAge,
+		},
+		Cycles: &bot.CycleConfig{
+			MaxLegs:      bot.DefaultMaxCycleLegs,
+			MinReturnBps: bot.DefaultMinReturnBps,
+			MaxQuoteAge:  bot.DefaultMaxQuoteAge,
+			SearchBudget: bot.DefaultCycleSearchBudget,
+		},
+		Execution: execution,
+		Risk: &bot.RiskConfig{
+			MaxTradeNotional:    map[string]decimal.Decimal{"BTC": decimal.New(1, -1)},
+			MaxExchangeExposure: map[string]decimal.Decimal{"BTC": decimal.New(5, -1)},
+			DailyLossLimit:      map[string]decimal.Decimal{"BTC": decimal.New(5, -2)},
+			MaxOrdersPerMinute:  bot.DefaultMaxOrdersPerMinute,
+		},
+		Rebalance: &bot.RebalanceConfig{
+			Targets: map[string]map[string]decimal.Decimal{
+				"BTC": {
+					bittrexExchange.GetName():  decimal.New(5, -1),
+					poloniexExchange.GetName(): decimal.New(5, -1),
+				},
+			},
+			Tolerance:           decimal.New(2, -1),
+			Via:                 []string{"LTC", "ETH"},
+			ConfirmationCostBps: decimal.New(1, 0),
+			Interval:            bot.DefaultRebalanceInterval,
+			TransferTimeout:     bot.DefaultTransferTimeout,
+		},
+	}, exchanges...)
+
+	// Metrics are scraped by Prometheus
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Printf("Metrics server error: %v", err)
+			}
+		}()
+	}
+
+	// Stop once all recorded data was replayed
+	if sequencer != nil {
+		go func() {
+			<-sequencer.Done()
+			if err := sequencer.Err(); err != nil {
+				log.Printf("Replay error: %v", err)
+			}
+			b.Quit()
+		}()
+	}
 
 	// Signals
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
 
-	// Goroutine Handle SIGINT and SIGTERM signals
+	// Goroutine Handle SIGINT and SIGTERM signals, SIGUSR1 engages the kill
+	// switch and SIGUSR2 resumes trading and resets the daily loss limit
 	go func() {
 		for {
 			select {
 			case s := <-sig:
 				log.Printf("Signal received: %v", s)
-				b.Quit()
+				switch {
+				case s == syscall.SIGUSR1 && b.Risk != nil:
+					b.Risk.Halt()
+				case s == syscall.SIGUSR2 && b.Risk != nil:
+					b.Risk.Resume()
+					b.Risk.ResetLossLimit()
+				case s == os.Interrupt || s == syscall.SIGTERM:
+					b.Quit()
+				}
 			}
 		}
 	}()
@@ -37,3 +277,111 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// activeMarkets returns pairs of markets open for trading on the exchange
+func activeMarkets(exchange *bittrex.Exchange, registry *symbols.Registry) ([]types.Pair, error) {
+	markets, err := exchange.GetMarkets()
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]types.Pair, 0, len(markets))
+	for _, m := range markets {
+		if !m.IsActive {
+			continue
+		}
+		pair, err := registry.ParsePair(exchange.GetName(), m.MarketName)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// newFeeModel returns trading fees of supported exchanges, overrides from the file are merged in unless path is empty
+func newFeeModel(path string) (*fees.Model, error) {
+	feeModel := fees.New(&fees.Config{
+		Exchanges: map[string]*fees.Schedule{
+			bittrex.Name: {
+				Rates: fees.Rates{Maker: bittrex.TradingFee, Taker: bittrex.TradingFee},
+			},
+			poloniex.Name: {
+				Rates: fees.Rates{Maker: poloniex.MakerFee, Taker: poloniex.TradingFee},
+			},
+		},
+	})
+
+	if path != "" {
+		cnf, err := readFeesConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := feeModel.Override(cnf); err != nil {
+			return nil, err
+		}
+	}
+
+	return feeModel, nil
+}
+
+// newRegistry returns default symbols extended by the file unless path is empty
+func newRegistry(path string) (*symbols.Registry, error) {
+	if path == "" {
+		return symbols.New(nil), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cnf, err := symbols.ReadConfig(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return symbols.New(cnf), nil
+}
+
+// paperExchanges wraps exchanges so they trade virtual balances read from the file
+func paperExchanges(path string, feeModel *fees.Model, exchanges []types.Exchange) ([]types.Exchange, error) {
+	balances, err := readBalances(path)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := make([]types.Exchange, len(exchanges))
+	for i, e := range exchanges {
+		log.Printf("[%s] Paper trading with balances: %v", e.GetName(), balances[e.GetName()])
+		wrapped[i] = paper.New(e, &paper.Config{
+			Balances:    balances[e.GetName()],
+			Fee:         feeModel.Taker(e.GetName(), types.Pair{}),
+			SlippageBps: bot.DefaultMaxSlippageBps,
+		})
+	}
+
+	return wrapped, nil
+}
+
+func readBalances(path string) (map[string]map[string]decimal.Decimal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return paper.ReadBalances(f)
+}
+
+func readFeesConfig(path string) (*fees.OverrideConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return fees.ReadConfig(f)
+}
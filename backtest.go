@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/backtest"
+	"github.com/RichardKnop/arbitrage/bittrex"
+	"github.com/RichardKnop/arbitrage/bot"
+	"github.com/RichardKnop/arbitrage/recorder"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoRecordedFiles is returned when the backtest glob matches nothing
+var ErrNoRecordedFiles = errors.New("No recorded files match")
+
+// runBacktest implements the backtest subcommand, e.g.
+// arbitrage backtest -files 'ticks/*.gz' -balances balances.json -trades trades.csv
+func runBacktest(args []string) error {
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	var (
+		files           = flags.String("files", "", "glob of recorded files")
+		from            = flags.String("from", "", "RFC3339 time records are replayed from, the first record when empty")
+		to              = flags.String("to", "", "RFC3339 time records are replayed until, the last record when empty")
+		balances        = flags.String("balances", "", "JSON file with starting balances per exchange, nothing is traded without it")
+		feesPath        = flags.String("fees", "", "JSON file with fee overrides merged into the default schedules")
+		minSpread       = flags.String("min-spread", bot.DefaultMinNetSpreadBps.String(), "minimum net spread in basis points")
+		minReturn       = flags.String("min-return", bot.DefaultMinReturnBps.String(), "minimum net return of triangular and cycle opportunities in basis points")
+		triangular      = flags.String("triangular", bittrex.Name, "exchange searched for triangular opportunities over its recorded markets, disabled when empty")
+		cycles          = flags.Bool("cycles", true, "search for multi-leg cycles across all exchanges")
+		maxQuoteAge     = flags.Duration("max-quote-age", bot.DefaultMaxQuoteAge, "older quotes are considered stale")
+		latency         = flags.Duration("latency", backtest.DefaultLatency, "delay between detection and orders reaching exchanges")
+		fillProbability = flags.Float64("fill-probability", backtest.DefaultFillProbability, "chance a leg fills when its price is still offered")
+		seed            = flags.Int64("seed", 1, "seed of random fills")
+		trades          = flags.String("trades", "", "CSV file per-trade detail is written to")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(*files)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return ErrNoRecordedFiles
+	}
+
+	minNetSpreadBps, err := decimal.NewFromString(*minSpread)
+	if err != nil {
+		return err
+	}
+
+	minReturnBps, err := decimal.NewFromString(*minReturn)
+	if err != nil {
+		return err
+	}
+
+	feeModel, err := newFeeModel(*feesPath)
+	if err != nil {
+		return err
+	}
+
+	cnf := &backtest.Config{
+		Files: paths,
+		Fees:  feeModel,
+		Spread: &bot.SpreadConfig{
+			MinNetSpreadBps: minNetSpreadBps,
+			MaxQuoteAge:     *maxQuoteAge,
+		},
+		Latency:         *latency,
+		FillProbability: *fillProbability,
+		Seed:            *seed,
+	}
+
+	// Triangles are built from markets the exchange quoted in the recording
+	if *triangular != "" {
+		markets, err := recordedMarkets(paths, *triangular)
+		if err != nil {
+			return err
+		}
+		cnf.Triangular = &bot.TriangularConfig{
+			Exchange:     *triangular,
+			Markets:      markets,
+			MinReturnBps: minReturnBps,
+			MaxQuoteAge:  *maxQuoteAge,
+		}
+	}
+
+	if *cycles {
+		cnf.Cycles = &bot.CycleConfig{
+			MaxLegs:      bot.DefaultMaxCycleLegs,
+			MinReturnBps: minReturnBps,
+			MaxQuoteAge:  *maxQuoteAge,
+		}
+	}
+
+	if cnf.From, err = parseTime(*from); err != nil {
+		return err
+	}
+	if cnf.To, err = parseTime(*to); err != nil {
+		return err
+	}
+
+	if *balances != "" {
+		if cnf.Balances, err = readBalances(*balances); err != nil {
+			return err
+		}
+	}
+
+	report, err := backtest.New(cnf).Run()
+	if err != nil {
+		return err
+	}
+
+	if *trades != "" {
+		f, err := os.Create(*trades)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := report.WriteTrades(f); err != nil {
+			return err
+		}
+	}
+
+	return report.WriteSummary(os.Stdout)
+}
+
+// recordedMarkets returns pairs of all tickers of the exchange in the recorded files
+func recordedMarkets(paths []string, exchange string) ([]types.Pair, error) {
+	records, err := recorder.OpenAll(paths)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+
+	var (
+		markets []types.Pair
+		seen    = make(map[types.Pair]bool)
+	)
+	for {
+		record, err := records.Next()
+		if err == io.EOF {
+			return markets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if record.Ticker == nil || record.Ticker.Exchange != exchange || seen[record.Ticker.Pair] {
+			continue
+		}
+		seen[record.Ticker.Pair] = true
+		markets = append(markets, record.Ticker.Pair)
+	}
+}
+
+// parseTime parses RFC3339 time, empty string is zero time
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,312 @@
+// Package backtest runs detectors and simulated execution over recorded market data and reports the results
+package backtest
+
+import (
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/bot"
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/recorder"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var one = decimal.New(1, 0)
+
+// TradeStatus ...
+type TradeStatus string
+
+const (
+	// TradeCompleted means both legs filled
+	TradeCompleted TradeStatus = "completed"
+	// TradeUnwound means a single leg filled and was reversed at the latest quote on its exchange
+	TradeUnwound TradeStatus = "unwound"
+	// TradeMissed means neither leg filled
+	TradeMissed TradeStatus = "missed"
+)
+
+// Trade is a simulated execution of a spread opportunity
+type Trade struct {
+	Pair         types.Pair
+	BuyExchange  string
+	SellExchange string
+	DetectedAt   time.Time
+	ExecutedAt   time.Time // detection plus latency
+	BuyPrice     decimal.Decimal
+	SellPrice    decimal.Decimal
+	Quantity     decimal.Decimal
+	BuyFilled    bool
+	SellFilled   bool
+	UnwindPrice  decimal.Decimal // price the filled leg was reversed at, zero unless unwound
+	Fees         decimal.Decimal // quote currency
+	GrossPnL     decimal.Decimal // quote currency, fees excluded
+	NetPnL       decimal.Decimal // quote currency, fees included
+	Status       TradeStatus
+}
+
+// Backtest runs detectors over recorded market data and simulates trading their opportunities
+type Backtest struct {
+	cnf        *Config
+	tickers    *bot.TickerStore
+	books      *bot.OrderBookStore
+	balances   *bot.BalanceStore
+	spread     *bot.SpreadDetector
+	triangular *bot.TriangularDetector
+	cycles     *bot.CycleDetector
+	wallets    map[string]map[string]decimal.Decimal
+	pending    []*Trade
+	inFlight   map[types.Pair]bool
+	random     *rand.Rand
+	report     *Report
+}
+
+// New returns new Backtest instance
+func New(cnf *Config) *Backtest {
+	if cnf.Fees == nil {
+		cnf.Fees = fees.New(new(fees.Config))
+	}
+
+	b := &Backtest{
+		cnf:      cnf,
+		tickers:  bot.NewTickerStore(),
+		books:    bot.NewOrderBookStore(),
+		balances: bot.NewBalanceStore(),
+		wallets:  make(map[string]map[string]decimal.Decimal),
+		inFlight: make(map[types.Pair]bool),
+		random:   rand.New(rand.NewSource(cnf.Seed)),
+		report:   newReport(),
+	}
+
+	for exchange, balances := range cnf.Balances {
+		b.wallets[exchange] = make(map[string]decimal.Decimal, len(balances))
+		for currency, amount := range balances {
+			b.wallets[exchange][currency] = amount
+		}
+		b.updateBalances(exchange)
+	}
+
+	if cnf.Spread != nil {
+		b.spread = bot.NewSpreadDetector(cnf.Spread, cnf.Fees, b.tickers, b.books, b.balances, bot.NewRulesStore())
+	}
+
+	if cnf.Triangular != nil {
+		b.triangular = bot.NewTriangularDetector(cnf.Triangular, cnf.Fees, b.tickers)
+	}
+
+	if cnf.Cycles != nil {
+		// Searches bounded by the wall clock would find more or fewer cycles depending on load
+		cycles := *cnf.Cycles
+		cycles.SearchBudget = 0
+		b.cycles = bot.NewCycleDetector(&cycles, cnf.Fees)
+	}
+
+	return b
+}
+
+// Run replays all records in the time range and returns the report
+func (b *Backtest) Run() (*Report, error) {
+	records, err := recorder.OpenAll(b.cnf.Files)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+
+	for {
+		record, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !b.cnf.From.IsZero() && record.ReceivedAt.Before(b.cnf.From) {
+			continue
+		}
+		if !b.cnf.To.IsZero() && record.ReceivedAt.After(b.cnf.To) {
+			break
+		}
+
+		// Orders reaching exchanges before this record see quotes as they were until now
+		b.settle(record.ReceivedAt)
+		b.handle(record)
+	}
+
+	// Trades still waiting for their latency see the last known quotes
+	b.settle(time.Time{})
+	b.report.finish(b.wallets)
+
+	return b.report, nil
+}
+
+func (b *Backtest) handle(record *recorder.Record) {
+	now := record.ReceivedAt
+	if b.report.From.IsZero() {
+		b.report.From = now
+	}
+	b.report.To = now
+	b.report.Records++
+
+	if record.Type == recorder.OrderBookRecord {
+		b.books.Update(record.OrderBook)
+		return
+	}
+
+	ticker := record.Ticker
+	b.tickers.Update(ticker)
+
+	if b.spread != nil {
+		for _, o := range b.spread.DetectAt(ticker, now) {
+			b.report.Opportunities++
+			b.trade(o)
+		}
+	}
+
+	if b.triangular != nil {
+		b.report.TriangularOpportunities += len(b.triangular.DetectAt(ticker, now))
+	}
+
+	if b.cycles != nil {
+		b.cycles.Update(ticker)
+		b.report.CycleOpportunities += len(b.cycles.SearchAt(now))
+	}
+}
+
+// trade reserves balances and schedules the opportunity for execution after the latency
+func (b *Backtest) trade(o *bot.Opportunity) {
+	if o.Quantity.Sign() <= 0 || b.inFlight[o.Pair] {
+		return
+	}
+	b.inFlight[o.Pair] = true
+
+	t := &Trade{
+		Pair:         o.Pair,
+		BuyExchange:  o.BuyExchange,
+		SellExchange: o.SellExchange,
+		DetectedAt:   o.DetectedAt,
+		ExecutedAt:   o.DetectedAt.Add(b.cnf.Latency),
+		BuyPrice:     o.BuyPrice,
+		SellPrice:    o.SellPrice,
+		Quantity:     o.Quantity,
+		UnwindPrice:  decimal.Zero,
+		Fees:         decimal.Zero,
+		GrossPnL:     decimal.Zero,
+		NetPnL:       decimal.Zero,
+	}
+	b.pending = append(b.pending, t)
+
+	b.add(t.BuyExchange, t.Pair.Quote, t.Quantity.Mul(t.BuyPrice).Mul(one.Add(b.takerFee(t.BuyExchange, t.Pair))).Neg())
+	b.add(t.SellExchange, t.Pair.Base, t.Quantity.Neg())
+}
+
+// settle executes pending trades which reached exchanges before the time, zero time settles all
+func (b *Backtest) settle(until time.Time) {
+	remaining := b.pending[:0]
+	for _, t := range b.pending {
+		if !until.IsZero() && t.ExecutedAt.After(until) {
+			remaining = append(remaining, t)
+			continue
+		}
+
+		b.execute(t)
+		delete(b.inFlight, t.Pair)
+		b.report.add(t)
+	}
+	b.pending = remaining
+}
+
+// execute fills legs whose limit price is still offered, subject to fill probability,
+// and reverses a lone filled leg at the latest quote
+func (b *Backtest) execute(t *Trade) {
+	buyFee := b.takerFee(t.BuyExchange, t.Pair)
+	sellFee := b.takerFee(t.SellExchange, t.Pair)
+	buyValue := t.Quantity.Mul(t.BuyPrice)
+	sellValue := t.Quantity.Mul(t.SellPrice)
+
+	if buy, ok := b.tickers.Get(t.BuyExchange, t.Pair); ok && !buy.Ask.GreaterThan(t.BuyPrice) {
+		t.BuyFilled = b.fills()
+	}
+	if sell, ok := b.tickers.Get(t.SellExchange, t.Pair); ok && !sell.Bid.LessThan(t.SellPrice) {
+		t.SellFilled = b.fills()
+	}
+
+	// Release what the legs which did not fill reserved
+	if t.BuyFilled {
+		b.add(t.BuyExchange, t.Pair.Base, t.Quantity)
+		t.GrossPnL = t.GrossPnL.Sub(buyValue)
+		t.Fees = t.Fees.Add(buyValue.Mul(buyFee))
+	} else {
+		b.add(t.BuyExchange, t.Pair.Quote, buyValue.Mul(one.Add(buyFee)))
+	}
+	if t.SellFilled {
+		b.add(t.SellExchange, t.Pair.Quote, sellValue.Mul(one.Sub(sellFee)))
+		t.GrossPnL = t.GrossPnL.Add(sellValue)
+		t.Fees = t.Fees.Add(sellValue.Mul(sellFee))
+	} else {
+		b.add(t.SellExchange, t.Pair.Base, t.Quantity)
+	}
+
+	switch {
+	case t.BuyFilled && t.SellFilled:
+		t.Status = TradeCompleted
+	case t.BuyFilled:
+		// Sell back what we bought on the buy exchange
+		ticker, _ := b.tickers.Get(t.BuyExchange, t.Pair)
+		t.UnwindPrice = ticker.Bid
+		value := t.Quantity.Mul(t.UnwindPrice)
+		b.add(t.BuyExchange, t.Pair.Base, t.Quantity.Neg())
+		b.add(t.BuyExchange, t.Pair.Quote, value.Mul(one.Sub(buyFee)))
+		t.GrossPnL = t.GrossPnL.Add(value)
+		t.Fees = t.Fees.Add(value.Mul(buyFee))
+		t.Status = TradeUnwound
+	case t.SellFilled:
+		// Buy back what we sold on the sell exchange
+		ticker, _ := b.tickers.Get(t.SellExchange, t.Pair)
+		t.UnwindPrice = ticker.Ask
+		value := t.Quantity.Mul(t.UnwindPrice)
+		b.add(t.SellExchange, t.Pair.Base, t.Quantity)
+		b.add(t.SellExchange, t.Pair.Quote, value.Mul(one.Add(sellFee)).Neg())
+		t.GrossPnL = t.GrossPnL.Sub(value)
+		t.Fees = t.Fees.Add(value.Mul(sellFee))
+		t.Status = TradeUnwound
+	default:
+		t.Status = TradeMissed
+	}
+
+	t.NetPnL = t.GrossPnL.Sub(t.Fees)
+}
+
+func (b *Backtest) fills() bool {
+	return b.cnf.FillProbability <= 0 || b.random.Float64() < b.cnf.FillProbability
+}
+
+func (b *Backtest) takerFee(exchange string, pair types.Pair) decimal.Decimal {
+	return b.cnf.Fees.Taker(exchange, pair)
+}
+
+// add changes the simulated balance and publishes it to the detector
+func (b *Backtest) add(exchange, currency string, amount decimal.Decimal) {
+	if _, ok := b.wallets[exchange]; !ok {
+		b.wallets[exchange] = make(map[string]decimal.Decimal)
+	}
+	b.wallets[exchange][currency] = b.wallets[exchange][currency].Add(amount)
+	b.updateBalances(exchange)
+}
+
+func (b *Backtest) updateBalances(exchange string) {
+	currencies := make([]string, 0, len(b.wallets[exchange]))
+	for currency := range b.wallets[exchange] {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	balances := make([]*types.Balance, len(currencies))
+	for i, currency := range currencies {
+		amount := b.wallets[exchange][currency]
+		balances[i] = &types.Balance{Currency: currency, Available: amount, Pending: decimal.Zero, Total: amount}
+	}
+	b.balances.Update(exchange, balances)
+}
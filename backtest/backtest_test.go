@@ -0,0 +1,176 @@
+package backtest
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/bot"
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/recorder"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGoldenReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := record(t, dir)
+
+	// The same recording and seed always produce the same report
+	var summary, trades []byte
+	for run := 0; run < 3; run++ {
+		report, err := New(newConfig(files)).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var s, tr bytes.Buffer
+		if err := report.WriteSummary(&s); err != nil {
+			t.Fatal(err)
+		}
+		if err := report.WriteTrades(&tr); err != nil {
+			t.Fatal(err)
+		}
+
+		if run > 0 && (!bytes.Equal(s.Bytes(), summary) || !bytes.Equal(tr.Bytes(), trades)) {
+			t.Fatalf("run %d report differs:\n%s\n%s", run, s.String(), tr.String())
+		}
+		summary, trades = s.Bytes(), tr.Bytes()
+	}
+
+	assertGolden(t, "summary.golden", summary)
+	assertGolden(t, "trades.golden", trades)
+}
+
+func newConfig(files []string) *Config {
+	return &Config{
+		Files: files,
+		Fees: fees.New(&fees.Config{
+			Exchanges: map[string]*fees.Schedule{
+				"bittrex":  {Rates: fees.Rates{Maker: decimal.New(25, -4), Taker: decimal.New(25, -4)}},
+				"poloniex": {Rates: fees.Rates{Maker: decimal.New(15, -4), Taker: decimal.New(25, -4)}},
+			},
+		}),
+		Spread: &bot.SpreadConfig{
+			MinNetSpreadBps: decimal.New(10, 0),
+			MaxQuoteAge:     time.Minute,
+		},
+		Triangular: &bot.TriangularConfig{
+			Exchange: "bittrex",
+			Markets: []types.Pair{
+				{Base: "LTC", Quote: "BTC"},
+				{Base: "ETH", Quote: "BTC"},
+				{Base: "LTC", Quote: "ETH"},
+			},
+			MinReturnBps: decimal.New(10, 0),
+			MaxQuoteAge:  time.Minute,
+		},
+		Cycles: &bot.CycleConfig{
+			MaxLegs:      bot.DefaultMaxCycleLegs,
+			MinReturnBps: decimal.New(10, 0),
+			MaxQuoteAge:  time.Minute,
+			// Ignored, backtests search without a time budget
+			SearchBudget: time.Nanosecond,
+		},
+		Balances: map[string]map[string]decimal.Decimal{
+			"bittrex":  {"BTC": decimal.New(1, 0), "LTC": decimal.New(10, 0)},
+			"poloniex": {"BTC": decimal.New(1, 0), "LTC": decimal.New(10, 0)},
+		},
+		Latency:         500 * time.Millisecond,
+		FillProbability: 0.7,
+		Seed:            1,
+	}
+}
+
+// record writes tickers of both exchanges, LTC/BTC is cheaper on bittrex every
+// other second and a triangle opens on bittrex now and then
+func record(t *testing.T, dir string) []string {
+	t.Helper()
+
+	r := recorder.New(&recorder.Config{Dir: dir})
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	ticker := func(exchange string, pair types.Pair, bid, ask string, at time.Time) *types.Ticker {
+		return &types.Ticker{
+			Exchange:   exchange,
+			Pair:       pair,
+			Bid:        mustDecimal(bid),
+			Ask:        mustDecimal(ask),
+			Last:       mustDecimal(bid),
+			Time:       at.Add(-100 * time.Millisecond),
+			ReceivedAt: at,
+		}
+	}
+	ltc := types.Pair{Base: "LTC", Quote: "BTC"}
+	eth := types.Pair{Base: "ETH", Quote: "BTC"}
+	ltcEth := types.Pair{Base: "LTC", Quote: "ETH"}
+
+	for i := 0; i < 20; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		tickers := []*types.Ticker{
+			ticker("poloniex", ltc, "0.0101", "0.0102", at),
+			ticker("bittrex", eth, "0.05", "0.0501", at.Add(100*time.Millisecond)),
+			ticker("bittrex", ltcEth, "0.2", "0.2004", at.Add(200*time.Millisecond)),
+		}
+		switch i % 4 {
+		case 0:
+			tickers = append(tickers, ticker("bittrex", ltc, "0.0099", "0.01", at.Add(300*time.Millisecond)))
+		case 2:
+			tickers = append(tickers, ticker("bittrex", ltc, "0.0105", "0.0106", at.Add(300*time.Millisecond)))
+		default:
+			tickers = append(tickers, ticker("bittrex", ltc, "0.01008", "0.01012", at.Add(300*time.Millisecond)))
+		}
+
+		for _, ticker := range tickers {
+			if err := r.RecordTicker(ticker, ticker.ReceivedAt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs, run with -update if the change is expected:\n%s", name, got)
+	}
+}
+
+func mustDecimal(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
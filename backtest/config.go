@@ -0,0 +1,36 @@
+package backtest
+
+import (
+	"time"
+
+	"github.com/RichardKnop/arbitrage/bot"
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// DefaultLatency ...
+	DefaultLatency = 500 * time.Millisecond
+	// DefaultFillProbability ...
+	DefaultFillProbability = 0.9
+)
+
+// Config stores backtest options
+type Config struct {
+	Files      []string              // recorded files, records of all files are merged by local receive time
+	From       time.Time             // records received earlier are skipped, zero means from the first one
+	To         time.Time             // records received later are skipped, zero means until the last one
+	Fees       *fees.Model           // trading fees charged on every simulated fill
+	Spread     *bot.SpreadConfig     // opportunities of this detector are traded
+	Triangular *bot.TriangularConfig // opportunities are counted only, disabled when nil
+	Cycles     *bot.CycleConfig      // opportunities are counted only, disabled when nil, searched without a time budget
+	// Balances available at the start keyed by exchange and currency, opportunities
+	// are sized by what we can afford and nothing is traded without them
+	Balances map[string]map[string]decimal.Decimal
+	// Latency between detecting an opportunity and orders reaching exchanges, legs
+	// fill only if quotes at that time still offer the detected prices
+	Latency time.Duration
+	// FillProbability is the chance a leg fills when its price is still offered, zero means always
+	FillProbability float64
+	Seed            int64 // seeds random fills so runs are reproducible
+}
@@ -0,0 +1,228 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// tradesHeader names columns of the per-trade CSV
+var tradesHeader = []string{
+	"detected_at", "executed_at", "pair", "buy_exchange", "sell_exchange", "buy_price", "sell_price",
+	"quantity", "buy_filled", "sell_filled", "unwind_price", "fees", "gross_pnl", "net_pnl", "status",
+}
+
+// Summary aggregates trades, P&L is keyed by quote currency as pairs may be quoted in different ones
+type Summary struct {
+	Trades    int
+	Completed int
+	Unwound   int
+	Missed    int
+	GrossPnL  map[string]decimal.Decimal
+	NetPnL    map[string]decimal.Decimal
+}
+
+// Report is the outcome of a backtest
+type Report struct {
+	From                    time.Time // receive time of the first replayed record
+	To                      time.Time // receive time of the last replayed record
+	Records                 int
+	Opportunities           int // spread opportunities, each detection counts
+	TriangularOpportunities int
+	CycleOpportunities      int
+	Trades                  []*Trade
+	Total                   *Summary
+	ByPair                  map[string]*Summary // keyed by pair, e.g. LTC/BTC
+	ByExchange              map[string]*Summary // keyed by buy and sell exchange, e.g. bittrex->poloniex
+	// MaxDrawdown is the largest fall of cumulative net P&L from its peak keyed by quote currency
+	MaxDrawdown map[string]decimal.Decimal
+	// Balances at the end keyed by exchange and currency
+	Balances map[string]map[string]decimal.Decimal
+	netPnL   map[string]decimal.Decimal
+	peak     map[string]decimal.Decimal
+}
+
+func newReport() *Report {
+	return &Report{
+		Total:       newSummary(),
+		ByPair:      make(map[string]*Summary),
+		ByExchange:  make(map[string]*Summary),
+		MaxDrawdown: make(map[string]decimal.Decimal),
+		netPnL:      make(map[string]decimal.Decimal),
+		peak:        make(map[string]decimal.Decimal),
+	}
+}
+
+func newSummary() *Summary {
+	return &Summary{
+		GrossPnL: make(map[string]decimal.Decimal),
+		NetPnL:   make(map[string]decimal.Decimal),
+	}
+}
+
+// WriteSummary writes a human readable report
+func (r *Report) WriteSummary(w io.Writer) error {
+	lines := []string{
+		fmt.Sprintf("Period: %s - %s", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339)),
+		fmt.Sprintf("Records: %d", r.Records),
+		fmt.Sprintf("Opportunities: %d spread, %d triangular, %d cycle", r.Opportunities, r.TriangularOpportunities, r.CycleOpportunities),
+		"Total: " + r.Total.String(),
+	}
+
+	for _, key := range sortedKeys(r.ByPair) {
+		lines = append(lines, fmt.Sprintf("Pair %s: %s", key, r.ByPair[key]))
+	}
+	for _, key := range sortedKeys(r.ByExchange) {
+		lines = append(lines, fmt.Sprintf("Exchanges %s: %s", key, r.ByExchange[key]))
+	}
+	lines = append(lines, "Max drawdown: "+formatAmounts(r.MaxDrawdown))
+
+	exchanges := make([]string, 0, len(r.Balances))
+	for exchange := range r.Balances {
+		exchanges = append(exchanges, exchange)
+	}
+	sort.Strings(exchanges)
+	for _, exchange := range exchanges {
+		lines = append(lines, fmt.Sprintf("Balances %s: %s", exchange, formatAmounts(r.Balances[exchange])))
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteTrades writes every trade as a CSV row
+func (r *Report) WriteTrades(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tradesHeader); err != nil {
+		return err
+	}
+
+	for _, t := range r.Trades {
+		err := writer.Write([]string{
+			t.DetectedAt.Format(time.RFC3339Nano),
+			t.ExecutedAt.Format(time.RFC3339Nano),
+			t.Pair.String(),
+			t.BuyExchange,
+			t.SellExchange,
+			t.BuyPrice.String(),
+			t.SellPrice.String(),
+			t.Quantity.String(),
+			strconv.FormatBool(t.BuyFilled),
+			strconv.FormatBool(t.SellFilled),
+			t.UnwindPrice.String(),
+			t.Fees.String(),
+			t.GrossPnL.String(),
+			t.NetPnL.String(),
+			string(t.Status),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// String ...
+func (s *Summary) String() string {
+	return fmt.Sprintf(
+		"trades: %d (completed: %d, unwound: %d, missed: %d), gross P&L: %s, net P&L: %s",
+		s.Trades,
+		s.Completed,
+		s.Unwound,
+		s.Missed,
+		formatAmounts(s.GrossPnL),
+		formatAmounts(s.NetPnL),
+	)
+}
+
+// add accounts a settled trade, trades settle in order of execution time
+func (r *Report) add(t *Trade) {
+	r.Trades = append(r.Trades, t)
+
+	pair := t.Pair.String()
+	if _, ok := r.ByPair[pair]; !ok {
+		r.ByPair[pair] = newSummary()
+	}
+	route := t.BuyExchange + "->" + t.SellExchange
+	if _, ok := r.ByExchange[route]; !ok {
+		r.ByExchange[route] = newSummary()
+	}
+
+	for _, s := range []*Summary{r.Total, r.ByPair[pair], r.ByExchange[route]} {
+		s.add(t)
+	}
+
+	quote := t.Pair.Quote
+	r.netPnL[quote] = r.netPnL[quote].Add(t.NetPnL)
+	if r.netPnL[quote].GreaterThan(r.peak[quote]) {
+		r.peak[quote] = r.netPnL[quote]
+	}
+	if drawdown := r.peak[quote].Sub(r.netPnL[quote]); drawdown.GreaterThan(r.MaxDrawdown[quote]) {
+		r.MaxDrawdown[quote] = drawdown
+	}
+}
+
+func (r *Report) finish(wallets map[string]map[string]decimal.Decimal) {
+	r.Balances = wallets
+}
+
+func (s *Summary) add(t *Trade) {
+	s.Trades++
+	switch t.Status {
+	case TradeCompleted:
+		s.Completed++
+	case TradeUnwound:
+		s.Unwound++
+	case TradeMissed:
+		s.Missed++
+	}
+
+	quote := t.Pair.Quote
+	s.GrossPnL[quote] = s.GrossPnL[quote].Add(t.GrossPnL)
+	s.NetPnL[quote] = s.NetPnL[quote].Add(t.NetPnL)
+}
+
+func sortedKeys(summaries map[string]*Summary) []string {
+	keys := make([]string, 0, len(summaries))
+	for key := range summaries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// formatAmounts formats amounts sorted by currency, e.g. 0.0012 BTC, 0.5 ETH
+func formatAmounts(amounts map[string]decimal.Decimal) string {
+	if len(amounts) == 0 {
+		return "-"
+	}
+
+	currencies := make([]string, 0, len(amounts))
+	for currency := range amounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	formatted := ""
+	for i, currency := range currencies {
+		if i > 0 {
+			formatted += ", "
+		}
+		formatted += amounts[currency].String() + " " + currency
+	}
+
+	return formatted
+}
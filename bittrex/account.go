@@ -0,0 +1,102 @@
+package bittrex
+
+import (
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// FetchBalances returns balances of all currencies using canonical currency symbols
+func (e *Exchange) FetchBalances() ([]*types.Balance, error) {
+	balances, err := e.GetBalances()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*types.Balance, len(balances))
+	for i, b := range balances {
+		result[i] = &types.Balance{
+			Currency:  e.cnf.Symbols.Currency(e.GetName(), b.Currency),
+			Available: b.Available,
+			Pending:   b.Pending,
+			Total:     b.Balance,
+		}
+	}
+
+	return result, nil
+}
+
+// FetchCurrencies returns transfer properties of all currencies using canonical currency symbols
+func (e *Exchange) FetchCurrencies() ([]*types.Currency, error) {
+	currencies, err := e.GetCurrencies()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*types.Currency, len(currencies))
+	for i, c := range currencies {
+		result[i] = &types.Currency{
+			Currency:         e.cnf.Symbols.Currency(e.GetName(), c.Currency),
+			WithdrawalFee:    c.TxFee,
+			MinConfirmations: c.MinConfirmation,
+			Active:           c.IsActive,
+		}
+	}
+
+	return result, nil
+}
+
+// RequestWithdrawal withdraws quantity of a canonical currency to the address, the memo
+// is sent as payment ID, and returns withdrawal ID
+func (e *Exchange) RequestWithdrawal(currency string, quantity decimal.Decimal, address *types.DepositAddress) (string, error) {
+	return e.Withdraw(e.cnf.Symbols.RawCurrency(e.GetName(), currency), quantity, address.Address, address.Memo)
+}
+
+// FetchDepositAddress returns address to deposit a canonical currency to. Currencies with
+// a base address share it and Bittrex returns the memo identifying our account instead.
+func (e *Exchange) FetchDepositAddress(currency string) (*types.DepositAddress, error) {
+	raw := e.cnf.Symbols.RawCurrency(e.GetName(), currency)
+	address, err := e.GetDepositAddress(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies, err := e.GetCurrencies()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range currencies {
+		if c.Currency == raw && c.BaseAddress != nil && *c.BaseAddress != "" {
+			return &types.DepositAddress{Address: *c.BaseAddress, Memo: address.Address}, nil
+		}
+	}
+
+	return &types.DepositAddress{Address: address.Address}, nil
+}
+
+// FetchTradingRules returns trading rules of all markets using canonical pairs
+func (e *Exchange) FetchTradingRules() ([]*types.TradingRules, error) {
+	markets, err := e.GetMarkets()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*types.TradingRules, 0, len(markets))
+	for _, m := range markets {
+		pair, err := e.cnf.Symbols.ParsePair(e.GetName(), m.MarketName)
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, &types.TradingRules{
+			Exchange:     e.GetName(),
+			Pair:         pair,
+			MinQuantity:  m.MinTradeSize,
+			MinNotional:  MinTradeValue[m.BaseCurrency],
+			PriceTick:    PriceTick,
+			QuantityStep: QuantityStep,
+			Active:       m.IsActive,
+		})
+	}
+
+	return rules, nil
+}
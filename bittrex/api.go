@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
 )
 
 const (
@@ -16,6 +19,10 @@ const (
 	GetCurrenciesEndpoint = "/public/getcurrencies"
 	// GetTickerEndpoint is a public endpoint to get tickers
 	GetTickerEndpoint = "/public/getticker"
+	// GetMarketSummariesEndpoint is a public endpoint to get summaries of all markets
+	GetMarketSummariesEndpoint = "/public/getmarketsummaries"
+	// GetOrderBookEndpoint is a public endpoint to get order book of a market
+	GetOrderBookEndpoint = "/public/getorderbook"
 )
 
 // GetMarkets ...
@@ -79,11 +86,88 @@ func (e *Exchange) GetTicker(market string) (*Ticker, error) {
 	return response.Result, nil
 }
 
+// GetMarketSummaries ...
+func (e *Exchange) GetMarketSummaries() ([]*MarketSummary, error) {
+	data, err := e.makeGetRequest(GetMarketSummariesEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(GetMarketSummariesResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, err
+	}
+
+	if !response.Success {
+		return nil, errors.New(response.Message)
+	}
+
+	return response.Result, nil
+}
+
+// GetOrderBook returns up to depth price levels on both sides of the market
+func (e *Exchange) GetOrderBook(market string, depth int) (*OrderBook, error) {
+	data, err := e.makeGetRequest(fmt.Sprintf("%s?market=%s&type=both&depth=%d", GetOrderBookEndpoint, market, depth))
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(GetOrderBookResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, err
+	}
+
+	if !response.Success {
+		return nil, errors.New(response.Message)
+	}
+
+	if response.Result == nil {
+		return nil, ErrEmptyResult
+	}
+
+	// Depth parameter is not always respected
+	if len(response.Result.Buy) > depth {
+		response.Result.Buy = response.Result.Buy[:depth]
+	}
+	if len(response.Result.Sell) > depth {
+		response.Result.Sell = response.Result.Sell[:depth]
+	}
+
+	return response.Result, nil
+}
+
 func (e *Exchange) makeGetRequest(path string) ([]byte, error) {
+	// Query string would give every market its own series
+	endpoint := strings.SplitN(path, "?", 2)[0]
+	start := time.Now()
+	defer func() {
+		requestDuration.Observe(time.Since(start).Seconds(), endpoint)
+	}()
+
 	resp, err := e.client.Get(e.cnf.Host + path)
 	if err != nil {
+		requestErrors.Inc(endpoint)
 		return []byte{}, err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		requestErrors.Inc(endpoint)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		requestErrors.Inc(endpoint)
+		return data, err
+	}
+
+	// Bittrex reports most failures in the envelope of a successful response
+	envelope := new(struct {
+		Success bool `json:"success"`
+	})
+	if resp.StatusCode < http.StatusBadRequest && (json.Unmarshal(data, envelope) != nil || !envelope.Success) {
+		requestErrors.Inc(endpoint)
+	}
 
-	return ioutil.ReadAll(resp.Body)
+	return data, nil
 }
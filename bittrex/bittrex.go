@@ -1,4 +1,7 @@
 // Package bittrex wraps the exchange API, see: https://bittrex.com/home/api
+//
+// Monetary fields of responses are decoded directly into decimals so prices never pass
+// through float64, JSON null values (e.g. bid of a market without orders) are decoded as zero.
 package bittrex
 
 import (
@@ -7,11 +10,12 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/RichardKnop/arbitrage/symbols"
 	"github.com/RichardKnop/arbitrage/types"
-	"github.com/shopspring/decimal"
 )
 
 const (
@@ -22,6 +26,10 @@ const (
 var (
 	// ErrEmptyResult is returned on edge case when response's success flag is true but result is null for some reason
 	ErrEmptyResult = errors.New("Empty result")
+	// ErrInvalidMarketName is returned when market name is not in QUOTE-BASE format
+	//
+	// Deprecated: symbols.ErrInvalidMarketName is returned by the registry.
+	ErrInvalidMarketName = errors.New("Invalid market name")
 )
 
 // Exchange wraps methods that interact with exchange
@@ -29,9 +37,12 @@ type Exchange struct {
 	cnf        *Config
 	client     *http.Client
 	quit       chan int
+	quitOnce   *sync.Once
 	wg         *sync.WaitGroup
 	batch      []string
 	batchCount int
+	nonceMu    *sync.Mutex
+	lastNonce  int64
 }
 
 // New returns new instance of Exchange
@@ -48,13 +59,31 @@ func New(cnf *Config) *Exchange {
 		},
 	}
 
+	if cnf.Symbols == nil {
+		cnf.Symbols = symbols.New(nil)
+	}
+
 	return &Exchange{
-		cnf:    cnf,
-		client: client,
-		quit:   make(chan int),
-		wg:     new(sync.WaitGroup),
-		batch:  make([]string, cnf.BatchSize),
+		cnf:      cnf,
+		client:   client,
+		quit:     make(chan int),
+		quitOnce: new(sync.Once),
+		wg:       new(sync.WaitGroup),
+		batch:    make([]string, cnf.BatchSize),
+		nonceMu:  new(sync.Mutex),
+	}
+}
+
+// ParseMarketName splits a market name such as BTC-LTC into LTC/BTC pair
+//
+// Deprecated: use symbols.Registry.ParsePair, which also applies currency aliases.
+func ParseMarketName(marketName string) (types.Pair, error) {
+	parts := strings.Split(marketName, "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.Pair{}, ErrInvalidMarketName
 	}
+
+	return types.Pair{Base: parts[1], Quote: parts[0]}, nil
 }
 
 // GetName returns a unique identifier for this exchange
@@ -67,6 +96,10 @@ func (e *Exchange) Run(tickers chan *types.Ticker) error {
 	errChan := make(chan error)
 
 	go func() {
+		if e.cnf.PollingMode == SummariesPolling {
+			errChan <- e.getTickersFromSummaries(tickers)
+			return
+		}
 		errChan <- e.getTickersInBatches(tickers)
 	}()
 
@@ -75,8 +108,11 @@ func (e *Exchange) Run(tickers chan *types.Ticker) error {
 
 // Quit ...
 func (e *Exchange) Quit() error {
+	// Closing the channel stops both ticker and order book loops
 	log.Printf("[%s] Quitting the ticker loop", e.GetName())
-	e.quit <- 1
+	e.quitOnce.Do(func() {
+		close(e.quit)
+	})
 
 	log.Printf("[%s] Wait for ticker goroutines to finish", e.GetName())
 	e.wg.Wait()
@@ -84,8 +120,32 @@ func (e *Exchange) Quit() error {
 	return nil
 }
 
+// RunOrderBooks ...
+func (e *Exchange) RunOrderBooks(books chan *types.OrderBook) error {
+	if len(e.cnf.OrderBookMarkets) == 0 {
+		return nil
+	}
+
+	for {
+		for _, marketName := range e.cnf.OrderBookMarkets {
+			// Capture quit channel here so we can exit the loop
+			select {
+			case <-e.quit:
+				return nil
+			case <-time.After(e.cnf.OrderBookInterval):
+			}
+
+			if err := e.getOrderBook(marketName, books); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
 func (e *Exchange) getTickersInBatches(tickers chan *types.Ticker) error {
 	for {
+		start := time.Now()
+
 		// Get all available markets
 		markets, err := e.GetMarkets()
 		if err != nil {
@@ -126,6 +186,59 @@ func (e *Exchange) getTickersInBatches(tickers chan *types.Ticker) error {
 				<-time.After(e.cnf.BatchInterval)
 			}
 		}
+
+		batchSweepDuration.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (e *Exchange) getTickersFromSummaries(tickers chan *types.Ticker) error {
+	for {
+		if err := e.getSummaries(tickers); err != nil {
+			log.Print(err)
+		}
+
+		// Capture quit channel here so we can exit the loop
+		select {
+		case <-e.quit:
+			return nil
+		case <-time.After(e.cnf.SummariesInterval):
+		}
+	}
+}
+
+func (e *Exchange) getSummaries(tickers chan *types.Ticker) error {
+	// A single request returns summaries of all markets
+	summaries, err := e.GetMarketSummaries()
+	if err != nil {
+		return fmt.Errorf("[%s] Get market summaries error: %v", e.GetName(), err)
+	}
+
+	now := time.Now()
+	for _, summary := range summaries {
+		pair, err := e.cnf.Symbols.ParsePair(e.GetName(), summary.MarketName)
+		if err != nil {
+			log.Printf("[%s] Parse market name '%s' error: %v", e.GetName(), summary.MarketName, err)
+			continue
+		}
+
+		// Summaries say when they were last updated, quotes of quiet markets can be old
+		quotedAt, err := time.Parse(TimeLayout, summary.TimeStamp)
+		if err != nil {
+			log.Printf("[%s] Parse time stamp '%s' of '%s' error: %v", e.GetName(), summary.TimeStamp, summary.MarketName, err)
+			quotedAt = now
+		}
+
+		// Push the ticker to the upstream channel
+		tickers <- &types.Ticker{
+			Exchange:   e.GetName(),
+			Market:     summary.MarketName,
+			Pair:       pair,
+			Bid:        summary.Bid,
+			Ask:        summary.Ask,
+			Last:       summary.Last,
+			Time:       quotedAt,
+			ReceivedAt: now,
+		}
 	}
 
 	return nil
@@ -139,19 +252,63 @@ func (e *Exchange) getTicker(marketName string, tickers chan *types.Ticker) erro
 		return nil
 	}
 
+	// Bittrex market names are quote currency first, e.g. BTC-LTC
+	pair, err := e.cnf.Symbols.ParsePair(e.GetName(), marketName)
+	if err != nil {
+		return fmt.Errorf("[%s] Parse market name '%s' error: %v", e.GetName(), marketName, err)
+	}
+
 	// Get the ticker for this market name
 	ticker, err := e.GetTicker(marketName)
 	if err != nil {
 		return fmt.Errorf("[%s] Get ticker for '%s' error: %v\n", e.GetName(), marketName, err)
 	}
 
-	// Push the ticker to the upstream channel
+	// Tickers carry no time stamp, receive time is the best we know
+	now := time.Now()
 	tickers <- &types.Ticker{
-		Bid:  decimal.NewFromFloat(ticker.Bid),
-		Ask:  decimal.NewFromFloat(ticker.Ask),
-		Last: decimal.NewFromFloat(ticker.Last),
-		Time: time.Now(),
+		Exchange:   e.GetName(),
+		Market:     marketName,
+		Pair:       pair,
+		Bid:        ticker.Bid,
+		Ask:        ticker.Ask,
+		Last:       ticker.Last,
+		Time:       now,
+		ReceivedAt: now,
 	}
 
 	return nil
 }
+
+func (e *Exchange) getOrderBook(marketName string, books chan *types.OrderBook) error {
+	pair, err := e.cnf.Symbols.ParsePair(e.GetName(), marketName)
+	if err != nil {
+		return fmt.Errorf("[%s] Parse market name '%s' error: %v", e.GetName(), marketName, err)
+	}
+
+	orderBook, err := e.GetOrderBook(marketName, e.cnf.OrderBookDepth)
+	if err != nil {
+		return fmt.Errorf("[%s] Get order book for '%s' error: %v", e.GetName(), marketName, err)
+	}
+
+	// Push the order book to the upstream channel
+	books <- &types.OrderBook{
+		Exchange: e.GetName(),
+		Market:   marketName,
+		Pair:     pair,
+		Bids:     priceLevels(orderBook.Buy),
+		Asks:     priceLevels(orderBook.Sell),
+		Time:     time.Now(),
+	}
+
+	return nil
+}
+
+func priceLevels(entries []*OrderBookEntry) []*types.PriceLevel {
+	levels := make([]*types.PriceLevel, len(entries))
+	for i, entry := range entries {
+		levels[i] = &types.PriceLevel{Price: entry.Rate, Quantity: entry.Quantity}
+	}
+
+	return levels
+}
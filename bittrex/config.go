@@ -2,6 +2,9 @@ package bittrex
 
 import (
 	"time"
+
+	"github.com/RichardKnop/arbitrage/symbols"
+	"github.com/shopspring/decimal"
 )
 
 const (
@@ -9,6 +12,35 @@ const (
 	DefaultBatchSize = 5
 	// DefaultBatchInterval ...
 	DefaultBatchInterval = 250 * time.Millisecond
+	// DefaultSummariesInterval ...
+	DefaultSummariesInterval = 2 * time.Second
+	// DefaultOrderBookDepth ...
+	DefaultOrderBookDepth = 20
+	// DefaultOrderBookInterval ...
+	DefaultOrderBookInterval = 500 * time.Millisecond
+)
+
+// PollingMode selects how tickers are fetched from the exchange
+type PollingMode string
+
+const (
+	// BatchPolling requests ticker of each market separately in batches
+	BatchPolling PollingMode = "batch"
+	// SummariesPolling requests summaries of all markets in a single request
+	SummariesPolling PollingMode = "summaries"
+)
+
+var (
+	// TradingFee is the commission rate charged on every trade
+	TradingFee = decimal.New(25, -4)
+	// PriceTick is the smallest price increment of all markets
+	PriceTick = decimal.New(1, -8)
+	// QuantityStep is the smallest quantity increment of all markets
+	QuantityStep = decimal.New(1, -8)
+	// MinTradeValue is the smallest order value accepted, keyed by quote currency (dust trade rule)
+	MinTradeValue = map[string]decimal.Decimal{
+		"BTC": decimal.New(5, -4),
+	}
 )
 
 // Config stores Bittrex configuration options
@@ -16,4 +48,13 @@ type Config struct {
 	Host          string
 	BatchSize     int           // specifies how many ticker requests we send at once before waiting for next batch
 	BatchInterval time.Duration // to space out ticker requests a bit so we don't DDOS the exchange
+	PollingMode   PollingMode   // defaults to batch polling when empty
+	// SummariesInterval is how long to wait between two market summaries requests in summaries polling mode
+	SummariesInterval time.Duration
+	OrderBookMarkets  []string          // markets to stream order books for, no order books are streamed when empty
+	OrderBookDepth    int               // number of price levels on each side
+	OrderBookInterval time.Duration     // to space out order book requests
+	Symbols           *symbols.Registry // maps market names to canonical pairs, default registry when nil
+	APIKey            string            // needed for private account and market endpoints only
+	APISecret         string            // used to sign requests to private endpoints
 }
@@ -0,0 +1,24 @@
+package bittrex
+
+import (
+	"github.com/RichardKnop/arbitrage/metrics"
+)
+
+var (
+	requestDuration = metrics.NewHistogram(
+		"arbitrage_bittrex_request_duration_seconds",
+		"Latency of API requests by endpoint.",
+		nil,
+		"endpoint",
+	)
+	requestErrors = metrics.NewCounter(
+		"arbitrage_bittrex_request_errors_total",
+		"API requests which failed, returned an HTTP error status or success false by endpoint.",
+		"endpoint",
+	)
+	batchSweepDuration = metrics.NewHistogram(
+		"arbitrage_bittrex_batch_sweep_duration_seconds",
+		"Time taken to request tickers of all markets in batches.",
+		[]float64{1, 5, 10, 30, 60, 120, 300, 600},
+	)
+)
@@ -0,0 +1,281 @@
+package bittrex
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// BuyLimitEndpoint is a private endpoint to place a limit buy order
+	BuyLimitEndpoint = "/market/buylimit"
+	// SellLimitEndpoint is a private endpoint to place a limit sell order
+	SellLimitEndpoint = "/market/selllimit"
+	// CancelEndpoint is a private endpoint to cancel an open order
+	CancelEndpoint = "/market/cancel"
+	// GetOpenOrdersEndpoint is a private endpoint to get open orders
+	GetOpenOrdersEndpoint = "/market/getopenorders"
+	// GetBalancesEndpoint is a private endpoint to get balances of all currencies
+	GetBalancesEndpoint = "/account/getbalances"
+	// GetBalanceEndpoint is a private endpoint to get balance of a single currency
+	GetBalanceEndpoint = "/account/getbalance"
+	// GetDepositAddressEndpoint is a private endpoint to get deposit address of a currency
+	GetDepositAddressEndpoint = "/account/getdepositaddress"
+	// WithdrawEndpoint is a private endpoint to withdraw funds
+	WithdrawEndpoint = "/account/withdraw"
+	// GetOrderEndpoint is a private endpoint to get a single order
+	GetOrderEndpoint = "/account/getorder"
+	// GetOrderHistoryEndpoint is a private endpoint to get closed orders
+	GetOrderHistoryEndpoint = "/account/getorderhistory"
+	// GetWithdrawalHistoryEndpoint is a private endpoint to get past withdrawals
+	GetWithdrawalHistoryEndpoint = "/account/getwithdrawalhistory"
+	// GetDepositHistoryEndpoint is a private endpoint to get past deposits
+	GetDepositHistoryEndpoint = "/account/getdeposithistory"
+)
+
+var (
+	// ErrMissingCredentials is returned when calling private endpoints without API key and secret
+	ErrMissingCredentials = errors.New("Missing API credentials")
+)
+
+// BuyLimit places a limit buy order and returns its UUID
+func (e *Exchange) BuyLimit(market string, quantity, rate decimal.Decimal) (string, error) {
+	return e.placeLimitOrder(BuyLimitEndpoint, market, quantity, rate)
+}
+
+// SellLimit places a limit sell order and returns its UUID
+func (e *Exchange) SellLimit(market string, quantity, rate decimal.Decimal) (string, error) {
+	return e.placeLimitOrder(SellLimitEndpoint, market, quantity, rate)
+}
+
+// Cancel ...
+func (e *Exchange) Cancel(uuid string) error {
+	return e.makePrivateRequest(CancelEndpoint, url.Values{"uuid": {uuid}}, nil)
+}
+
+// GetOpenOrders returns open orders, all markets are included when market is empty
+func (e *Exchange) GetOpenOrders(market string) ([]*OpenOrder, error) {
+	params := url.Values{}
+	if market != "" {
+		params.Set("market", market)
+	}
+
+	var orders []*OpenOrder
+	if err := e.makePrivateRequest(GetOpenOrdersEndpoint, params, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetBalances ...
+func (e *Exchange) GetBalances() ([]*Balance, error) {
+	var balances []*Balance
+	if err := e.makePrivateRequest(GetBalancesEndpoint, url.Values{}, &balances); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// GetBalance ...
+func (e *Exchange) GetBalance(currency string) (*Balance, error) {
+	balance := new(Balance)
+	if err := e.makePrivateRequest(GetBalanceEndpoint, url.Values{"currency": {currency}}, balance); err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
+// GetDepositAddress ...
+func (e *Exchange) GetDepositAddress(currency string) (*DepositAddress, error) {
+	address := new(DepositAddress)
+	if err := e.makePrivateRequest(GetDepositAddressEndpoint, url.Values{"currency": {currency}}, address); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// Withdraw sends funds to an address and returns UUID of the withdrawal, payment ID is optional
+func (e *Exchange) Withdraw(currency string, quantity decimal.Decimal, address, paymentID string) (string, error) {
+	params := url.Values{
+		"currency": {currency},
+		"quantity": {quantity.String()},
+		"address":  {address},
+	}
+	if paymentID != "" {
+		params.Set("paymentid", paymentID)
+	}
+
+	result := new(UUIDResult)
+	if err := e.makePrivateRequest(WithdrawEndpoint, params, result); err != nil {
+		return "", err
+	}
+
+	return result.UUID, nil
+}
+
+// GetOrder ...
+func (e *Exchange) GetOrder(uuid string) (*Order, error) {
+	order := new(Order)
+	if err := e.makePrivateRequest(GetOrderEndpoint, url.Values{"uuid": {uuid}}, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetOrderHistory returns closed orders, all markets are included when market is empty
+func (e *Exchange) GetOrderHistory(market string) ([]*OrderHistory, error) {
+	params := url.Values{}
+	if market != "" {
+		params.Set("market", market)
+	}
+
+	var orders []*OrderHistory
+	if err := e.makePrivateRequest(GetOrderHistoryEndpoint, params, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetWithdrawalHistory returns past withdrawals, all currencies are included when currency is empty
+func (e *Exchange) GetWithdrawalHistory(currency string) ([]*Withdrawal, error) {
+	params := url.Values{}
+	if currency != "" {
+		params.Set("currency", currency)
+	}
+
+	var withdrawals []*Withdrawal
+	if err := e.makePrivateRequest(GetWithdrawalHistoryEndpoint, params, &withdrawals); err != nil {
+		return nil, err
+	}
+
+	return withdrawals, nil
+}
+
+// GetDepositHistory returns past deposits, all currencies are included when currency is empty
+func (e *Exchange) GetDepositHistory(currency string) ([]*Deposit, error) {
+	params := url.Values{}
+	if currency != "" {
+		params.Set("currency", currency)
+	}
+
+	var deposits []*Deposit
+	if err := e.makePrivateRequest(GetDepositHistoryEndpoint, params, &deposits); err != nil {
+		return nil, err
+	}
+
+	return deposits, nil
+}
+
+func (e *Exchange) placeLimitOrder(path, market string, quantity, rate decimal.Decimal) (string, error) {
+	params := url.Values{
+		"market":   {market},
+		"quantity": {quantity.String()},
+		"rate":     {rate.String()},
+	}
+
+	result := new(UUIDResult)
+	if err := e.makePrivateRequest(path, params, result); err != nil {
+		return "", err
+	}
+
+	return result.UUID, nil
+}
+
+// HasCredentials returns true when API key and secret needed by private endpoints are configured
+func (e *Exchange) HasCredentials() bool {
+	return e.cnf.APIKey != "" && e.cnf.APISecret != ""
+}
+
+// makePrivateRequest adds API key and nonce to the query, signs the full URI with
+// HMAC-SHA512 of the API secret and decodes the result into the result argument
+func (e *Exchange) makePrivateRequest(path string, params url.Values, result interface{}) error {
+	if !e.HasCredentials() {
+		return ErrMissingCredentials
+	}
+
+	start := time.Now()
+	err := e.signedRequest(path, params, result)
+	requestDuration.Observe(time.Since(start).Seconds(), path)
+	if err != nil {
+		requestErrors.Inc(path)
+	}
+
+	return err
+}
+
+func (e *Exchange) signedRequest(path string, params url.Values, result interface{}) error {
+	params.Set("apikey", e.cnf.APIKey)
+	params.Set("nonce", strconv.FormatInt(e.nextNonce(), 10))
+	uri := e.cnf.Host + path + "?" + params.Encode()
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apisign", sign(uri, e.cnf.APISecret))
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	response := new(Response)
+	if err := json.Unmarshal(data, response); err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return errors.New(response.Message)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if len(response.Result) == 0 || string(response.Result) == "null" {
+		return ErrEmptyResult
+	}
+
+	return json.Unmarshal(response.Result, result)
+}
+
+// nextNonce returns strictly increasing nonce even when called many times within the same nanosecond
+func (e *Exchange) nextNonce() int64 {
+	e.nonceMu.Lock()
+	defer e.nonceMu.Unlock()
+
+	nonce := time.Now().UnixNano()
+	if nonce <= e.lastNonce {
+		nonce = e.lastNonce + 1
+	}
+	e.lastNonce = nonce
+
+	return nonce
+}
+
+func sign(uri, secret string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(uri))
+	return hex.EncodeToString(mac.Sum(nil))
+}
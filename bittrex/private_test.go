@@ -0,0 +1,200 @@
+package bittrex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		uri    string
+		secret string
+		want   string
+	}{
+		// RFC 4231 test case 2
+		{
+			uri:    "what do ya want for nothing?",
+			secret: "Jefe",
+			want:   "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+		},
+		{
+			uri:    "https://bittrex.com/api/v1.1/account/getbalance?apikey=key&currency=BTC&nonce=1500000000000000000",
+			secret: "secret",
+			want:   "d497a912556171479cd244205972ae927598930e49f5241687df00574e92e529cf5d95f38a6bfac38513197a8ec234bd5fb61c8bd46bb94f717e4fc36bbf6cf8",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := sign(tt.uri, tt.secret); got != tt.want {
+			t.Errorf("sign(%q) = %s, want %s", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestPrivateRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiKey   string
+		response string
+		balance  string // decoded from the result, none when empty
+		err      string
+	}{
+		{
+			name:     "result",
+			apiKey:   "key",
+			response: `{"success":true,"message":"","result":{"Currency":"BTC","Balance":1.5,"Available":1.25,"Pending":0}}`,
+			balance:  "1.25",
+		},
+		{
+			name:     "not successful",
+			apiKey:   "key",
+			response: `{"success":false,"message":"INVALID_SIGNATURE","result":null}`,
+			err:      "INVALID_SIGNATURE",
+		},
+		{
+			name:     "null result",
+			apiKey:   "key",
+			response: `{"success":true,"message":"","result":null}`,
+			err:      ErrEmptyResult.Error(),
+		},
+		{
+			name:     "invalid response",
+			apiKey:   "key",
+			response: `<html>`,
+			err:      "invalid character '<' looking for beginning of value",
+		},
+		{
+			name: "missing credentials",
+			err:  ErrMissingCredentials.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+
+				query := r.URL.Query()
+				if r.URL.Path != GetBalanceEndpoint {
+					t.Errorf("path = %s, want %s", r.URL.Path, GetBalanceEndpoint)
+				}
+				if apiKey := query.Get("apikey"); apiKey != tt.apiKey {
+					t.Errorf("apikey = %s, want %s", apiKey, tt.apiKey)
+				}
+				if currency := query.Get("currency"); currency != "BTC" {
+					t.Errorf("currency = %s, want BTC", currency)
+				}
+				if _, err := strconv.ParseInt(query.Get("nonce"), 10, 64); err != nil {
+					t.Errorf("nonce error: %v", err)
+				}
+
+				// Signature covers the full URI including the nonce
+				uri := "http://" + r.Host + r.URL.RequestURI()
+				if apiSign := r.Header.Get("apisign"); apiSign != sign(uri, "secret") {
+					t.Errorf("apisign = %s, want signature of %s", apiSign, uri)
+				}
+
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			e := New(&Config{Host: server.URL, APIKey: tt.apiKey, APISecret: "secret"})
+			balance, err := e.GetBalance("BTC")
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if requests != 1 {
+				t.Errorf("got %d requests, want 1", requests)
+			}
+
+			if want, _ := decimal.NewFromString(tt.balance); !balance.Available.Equal(want) {
+				t.Errorf("available = %s, want %s", balance.Available, want)
+			}
+		})
+	}
+}
+
+func TestCancelWithoutResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if uuid := r.URL.Query().Get("uuid"); uuid != "order" {
+			t.Errorf("uuid = %s, want order", uuid)
+		}
+		fmt.Fprint(w, `{"success":true,"message":"","result":null}`)
+	}))
+	defer server.Close()
+
+	// Cancel returns no result so null is not an error
+	e := New(&Config{Host: server.URL, APIKey: "key", APISecret: "secret"})
+	if err := e.Cancel("order"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNextNonce(t *testing.T) {
+	const (
+		goroutines = 8
+		calls      = 1000
+	)
+
+	e := New(new(Config))
+	nonces := make([][]int64, goroutines)
+
+	var wg sync.WaitGroup
+	for i := range nonces {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < calls; j++ {
+				nonces[i] = append(nonces[i], e.nextNonce())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool, goroutines*calls)
+	for i := range nonces {
+		for j, nonce := range nonces[i] {
+			if seen[nonce] {
+				t.Fatalf("nonce %d used twice", nonce)
+			}
+			seen[nonce] = true
+
+			if j > 0 && nonce <= nonces[i][j-1] {
+				t.Fatalf("nonce %d after %d is not increasing", nonce, nonces[i][j-1])
+			}
+		}
+	}
+}
+
+func TestHasCredentials(t *testing.T) {
+	tests := []struct {
+		apiKey, apiSecret string
+		want              bool
+	}{
+		{"", "", false},
+		{"key", "", false},
+		{"", "secret", false},
+		{"key", "secret", true},
+	}
+
+	for _, tt := range tests {
+		e := New(&Config{APIKey: tt.apiKey, APISecret: tt.apiSecret})
+		if got := e.HasCredentials(); got != tt.want {
+			t.Errorf("key %q secret %q has credentials = %v, want %v", tt.apiKey, tt.apiSecret, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package bittrex
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// TimeLayout is used by Bittrex for all timestamps, they are in UTC
+	TimeLayout = "2006-01-02T15:04:05.999999999"
+	// LimitBuy order type
+	LimitBuy = "LIMIT_BUY"
+	// LimitSell order type
+	LimitSell = "LIMIT_SELL"
+)
+
+var (
+	// ErrUnknownOrderType is returned for orders other than limit buy or sell
+	ErrUnknownOrderType = errors.New("Unknown order type")
+)
+
+// PlaceOrder places a limit order on the market of the pair
+func (e *Exchange) PlaceOrder(pair types.Pair, side types.Side, price, quantity decimal.Decimal) (*types.Order, error) {
+	market, err := e.cnf.Symbols.MarketName(e.GetName(), pair)
+	if err != nil {
+		return nil, err
+	}
+
+	var uuid string
+	if side == types.Buy {
+		uuid, err = e.BuyLimit(market, quantity, price)
+	} else {
+		uuid, err = e.SellLimit(market, quantity, price)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &types.Order{
+		ID:             uuid,
+		Exchange:       e.GetName(),
+		Pair:           pair,
+		Side:           side,
+		Price:          price,
+		Quantity:       quantity,
+		FilledQuantity: decimal.Zero,
+		AveragePrice:   decimal.Zero,
+		Fee:            decimal.Zero,
+		Status:         types.OrderOpen,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// CancelOrder ...
+func (e *Exchange) CancelOrder(id string) error {
+	return e.Cancel(id)
+}
+
+// FetchOrder ...
+func (e *Exchange) FetchOrder(id string) (*types.Order, error) {
+	o, err := e.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := e.newOrder(o.OrderUUID, o.Exchange, o.Type, o.Limit, o.Quantity, o.QuantityRemaining, o.PricePerUnit, o.CommissionPaid, o.Opened)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case o.IsOpen:
+		// Status of open orders depends on filled quantity only
+	case order.FilledQuantity.Equal(order.Quantity):
+		order.Status = types.OrderFilled
+	default:
+		order.Status = types.OrderCancelled
+	}
+
+	if o.Closed != nil {
+		if closed, err := time.Parse(TimeLayout, *o.Closed); err == nil {
+			order.UpdatedAt = closed
+		}
+	}
+
+	return order, nil
+}
+
+// FetchOpenOrders ...
+func (e *Exchange) FetchOpenOrders(pair types.Pair) ([]*types.Order, error) {
+	var market string
+	if pair != (types.Pair{}) {
+		var err error
+		market, err = e.cnf.Symbols.MarketName(e.GetName(), pair)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	openOrders, err := e.GetOpenOrders(market)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*types.Order, 0, len(openOrders))
+	for _, o := range openOrders {
+		order, err := e.newOrder(o.OrderUUID, o.Exchange, o.OrderType, o.Limit, o.Quantity, o.QuantityRemaining, o.PricePerUnit, o.CommissionPaid, o.Opened)
+		// Orders placed by hand, e.g. conditional ones, are not ours to manage
+		if err == ErrUnknownOrderType {
+			log.Printf("[%s] Skipping open order %s of type %s", e.GetName(), o.OrderUUID, o.OrderType)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// newOrder converts fields shared by open orders and orders into an open order
+func (e *Exchange) newOrder(id, market, orderType string, limit, quantity, remaining, pricePerUnit, commission decimal.Decimal, opened string) (*types.Order, error) {
+	pair, err := e.cnf.Symbols.ParsePair(e.GetName(), market)
+	if err != nil {
+		return nil, err
+	}
+
+	var side types.Side
+	switch orderType {
+	case LimitBuy:
+		side = types.Buy
+	case LimitSell:
+		side = types.Sell
+	default:
+		return nil, ErrUnknownOrderType
+	}
+
+	createdAt, err := time.Parse(TimeLayout, opened)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &types.Order{
+		ID:             id,
+		Exchange:       e.GetName(),
+		Pair:           pair,
+		Side:           side,
+		Price:          limit,
+		Quantity:       quantity,
+		FilledQuantity: quantity.Sub(remaining),
+		AveragePrice:   pricePerUnit,
+		Fee:            commission,
+		Status:         types.OrderOpen,
+		CreatedAt:      createdAt,
+		UpdatedAt:      time.Now(),
+	}
+
+	if order.FilledQuantity.Sign() > 0 {
+		order.Status = types.OrderPartiallyFilled
+	}
+
+	return order, nil
+}
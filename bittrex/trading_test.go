@@ -0,0 +1,166 @@
+package bittrex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// newTradingServer responds to private requests of the endpoint with the result
+func newTradingServer(t *testing.T, endpoint, result string) (*Exchange, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != endpoint {
+			t.Errorf("path = %s, want %s", r.URL.Path, endpoint)
+		}
+		fmt.Fprintf(w, `{"success":true,"message":"","result":%s}`, result)
+	}))
+
+	return New(&Config{Host: server.URL, APIKey: "key", APISecret: "secret"}), server
+}
+
+func TestFetchOrder(t *testing.T) {
+	closed := `"2018-01-02T03:04:06.5"`
+
+	tests := []struct {
+		name      string
+		orderType string
+		remaining string
+		isOpen    bool
+		closed    string
+		side      types.Side
+		status    types.OrderStatus
+		filled    string
+		err       error
+	}{
+		{
+			name:      "open",
+			orderType: LimitBuy,
+			remaining: "2",
+			isOpen:    true,
+			closed:    "null",
+			side:      types.Buy,
+			status:    types.OrderOpen,
+			filled:    "0",
+		},
+		{
+			name:      "open partially filled",
+			orderType: LimitSell,
+			remaining: "0.5",
+			isOpen:    true,
+			closed:    "null",
+			side:      types.Sell,
+			status:    types.OrderPartiallyFilled,
+			filled:    "1.5",
+		},
+		{
+			name:      "closed filled",
+			orderType: LimitBuy,
+			remaining: "0",
+			closed:    closed,
+			side:      types.Buy,
+			status:    types.OrderFilled,
+			filled:    "2",
+		},
+		{
+			name:      "closed partially filled",
+			orderType: LimitBuy,
+			remaining: "0.5",
+			closed:    closed,
+			side:      types.Buy,
+			status:    types.OrderCancelled,
+			filled:    "1.5",
+		},
+		{
+			name:      "closed without fills",
+			orderType: LimitSell,
+			remaining: "2",
+			closed:    closed,
+			side:      types.Sell,
+			status:    types.OrderCancelled,
+			filled:    "0",
+		},
+		{
+			name:      "conditional",
+			orderType: "CONDITIONAL_BUY",
+			remaining: "2",
+			closed:    "null",
+			err:       ErrUnknownOrderType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, server := newTradingServer(t, GetOrderEndpoint, fmt.Sprintf(`{
+				"OrderUuid": "order",
+				"Exchange": "BTC-LTC",
+				"Type": %q,
+				"Quantity": 2,
+				"QuantityRemaining": %s,
+				"Limit": 0.01,
+				"CommissionPaid": 0.00005,
+				"PricePerUnit": 0.0099,
+				"Opened": "2018-01-02T03:04:05.123",
+				"Closed": %s,
+				"IsOpen": %v
+			}`, tt.orderType, tt.remaining, tt.closed, tt.isOpen))
+			defer server.Close()
+
+			order, err := e.FetchOrder("order")
+			if err != tt.err {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if order.ID != "order" || order.Exchange != Name || order.Pair != (types.Pair{Base: "LTC", Quote: "BTC"}) {
+				t.Errorf("order = %+v", order)
+			}
+			if order.Side != tt.side || order.Status != tt.status {
+				t.Errorf("order = %s %s, want %s %s", order.Side, order.Status, tt.side, tt.status)
+			}
+			assertDecimal(t, "filled", order.FilledQuantity, tt.filled)
+			assertDecimal(t, "price", order.Price, "0.01")
+			assertDecimal(t, "average price", order.AveragePrice, "0.0099")
+			assertDecimal(t, "fee", order.Fee, "0.00005")
+
+			if createdAt := time.Date(2018, 1, 2, 3, 4, 5, 123000000, time.UTC); !order.CreatedAt.Equal(createdAt) {
+				t.Errorf("created at = %s, want %s", order.CreatedAt, createdAt)
+			}
+			// Closed orders were last updated when they closed
+			closedAt := time.Date(2018, 1, 2, 3, 4, 6, 500000000, time.UTC)
+			if !tt.isOpen && !order.UpdatedAt.Equal(closedAt) {
+				t.Errorf("updated at = %s, want %s", order.UpdatedAt, closedAt)
+			}
+		})
+	}
+}
+
+func TestFetchOpenOrders(t *testing.T) {
+	e, server := newTradingServer(t, GetOpenOrdersEndpoint, `[
+		{"OrderUuid": "buy", "Exchange": "BTC-LTC", "OrderType": "LIMIT_BUY", "Quantity": 2, "QuantityRemaining": 2, "Limit": 0.01, "Opened": "2018-01-02T03:04:05"},
+		{"OrderUuid": "stop", "Exchange": "BTC-LTC", "OrderType": "CONDITIONAL_SELL", "Quantity": 2, "QuantityRemaining": 2, "Limit": 0.009, "Opened": "2018-01-02T03:04:05"},
+		{"OrderUuid": "sell", "Exchange": "BTC-ETH", "OrderType": "LIMIT_SELL", "Quantity": 1, "QuantityRemaining": 0.25, "Limit": 0.05, "Opened": "2018-01-02T03:04:05"}
+	]`)
+	defer server.Close()
+
+	// Orders of unknown types are skipped rather than failing the rest
+	orders, err := e.FetchOpenOrders(types.Pair{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("got %d orders, want 2", len(orders))
+	}
+	if orders[0].ID != "buy" || orders[0].Side != types.Buy || orders[0].Status != types.OrderOpen {
+		t.Errorf("first order = %+v, want open buy", orders[0])
+	}
+	if orders[1].ID != "sell" || orders[1].Side != types.Sell || orders[1].Status != types.OrderPartiallyFilled {
+		t.Errorf("second order = %+v, want partially filled sell", orders[1])
+	}
+	assertDecimal(t, "filled", orders[1].FilledQuantity, "0.75")
+}
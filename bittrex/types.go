@@ -1,5 +1,12 @@
 package bittrex
 
+import (
+	"encoding/json"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
 // GetMarketsResponse ...
 type GetMarketsResponse struct {
 	Success bool      `json:"success"`
@@ -13,12 +20,19 @@ type Market struct {
 	BaseCurrency       string
 	MarketCurrencyLong string
 	BaseCurrencyLong   string
-	MinTradeSize       float64
+	MinTradeSize       decimal.Decimal
 	MarketName         string
 	IsActive           bool
 	Created            string
 }
 
+// Pair returns the market as a pair, Bittrex base currency is the quote currency of the pair
+//
+// Deprecated: use symbols.Registry.ParsePair with MarketName, which also applies currency aliases.
+func (m *Market) Pair() types.Pair {
+	return types.Pair{Base: m.MarketCurrency, Quote: m.BaseCurrency}
+}
+
 // GetCurrenciesResponse ...
 type GetCurrenciesResponse struct {
 	Success bool        `json:"success"`
@@ -31,7 +45,7 @@ type Currency struct {
 	Currency        string
 	CurrencyLong    string
 	MinConfirmation int
-	TxFee           float64
+	TxFee           decimal.Decimal
 	IsActive        bool
 	CoinType        string
 	BaseAddress     *string
@@ -46,7 +60,171 @@ type GetTickerResponse struct {
 
 // Ticker ...
 type Ticker struct {
-	Bid  float64
-	Ask  float64
-	Last float64
+	Bid  decimal.Decimal
+	Ask  decimal.Decimal
+	Last decimal.Decimal
+}
+
+// GetMarketSummariesResponse ...
+type GetMarketSummariesResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message"`
+	Result  []*MarketSummary `json:"result"`
+}
+
+// MarketSummary ...
+type MarketSummary struct {
+	MarketName     string
+	High           decimal.Decimal
+	Low            decimal.Decimal
+	Volume         decimal.Decimal
+	Last           decimal.Decimal
+	BaseVolume     decimal.Decimal
+	TimeStamp      string
+	Bid            decimal.Decimal
+	Ask            decimal.Decimal
+	OpenBuyOrders  int
+	OpenSellOrders int
+	PrevDay        decimal.Decimal
+	Created        string
+}
+
+// GetOrderBookResponse ...
+type GetOrderBookResponse struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message"`
+	Result  *OrderBook `json:"result"`
+}
+
+// OrderBook ...
+type OrderBook struct {
+	Buy  []*OrderBookEntry `json:"buy"`
+	Sell []*OrderBookEntry `json:"sell"`
+}
+
+// OrderBookEntry ...
+type OrderBookEntry struct {
+	Quantity decimal.Decimal
+	Rate     decimal.Decimal
+}
+
+// Response is a generic response envelope, result is decoded separately by each endpoint
+type Response struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// UUIDResult is returned by endpoints creating orders or withdrawals
+type UUIDResult struct {
+	UUID string `json:"uuid"`
+}
+
+// OpenOrder ...
+type OpenOrder struct {
+	UUID              *string `json:"Uuid"`
+	OrderUUID         string  `json:"OrderUuid"`
+	Exchange          string
+	OrderType         string
+	Quantity          decimal.Decimal
+	QuantityRemaining decimal.Decimal
+	Limit             decimal.Decimal
+	CommissionPaid    decimal.Decimal
+	Price             decimal.Decimal
+	PricePerUnit      decimal.Decimal
+	Opened            string
+	Closed            *string
+	CancelInitiated   bool
+	ImmediateOrCancel bool
+	IsConditional     bool
+	Condition         string
+	ConditionTarget   *string
+}
+
+// Balance ...
+type Balance struct {
+	Currency      string
+	Balance       decimal.Decimal
+	Available     decimal.Decimal
+	Pending       decimal.Decimal
+	CryptoAddress *string
+	Requested     bool
+	UUID          *string `json:"Uuid"`
+}
+
+// DepositAddress ...
+type DepositAddress struct {
+	Currency string
+	Address  string
+}
+
+// Order ...
+type Order struct {
+	AccountID                  *string `json:"AccountId"`
+	OrderUUID                  string  `json:"OrderUuid"`
+	Exchange                   string
+	Type                       string
+	Quantity                   decimal.Decimal
+	QuantityRemaining          decimal.Decimal
+	Limit                      decimal.Decimal
+	Reserved                   decimal.Decimal
+	ReserveRemaining           decimal.Decimal
+	CommissionReserved         decimal.Decimal
+	CommissionReserveRemaining decimal.Decimal
+	CommissionPaid             decimal.Decimal
+	Price                      decimal.Decimal
+	PricePerUnit               decimal.Decimal
+	Opened                     string
+	Closed                     *string
+	IsOpen                     bool
+	Sentinel                   string
+	CancelInitiated            bool
+	ImmediateOrCancel          bool
+	IsConditional              bool
+	Condition                  string
+	ConditionTarget            *string
+}
+
+// OrderHistory ...
+type OrderHistory struct {
+	OrderUUID         string `json:"OrderUuid"`
+	Exchange          string
+	TimeStamp         string
+	OrderType         string
+	Limit             decimal.Decimal
+	Quantity          decimal.Decimal
+	QuantityRemaining decimal.Decimal
+	Commission        decimal.Decimal
+	Price             decimal.Decimal
+	PricePerUnit      decimal.Decimal
+	IsConditional     bool
+	Condition         string
+	ConditionTarget   *string
+	ImmediateOrCancel bool
+}
+
+// Withdrawal ...
+type Withdrawal struct {
+	PaymentUUID    string `json:"PaymentUuid"`
+	Currency       string
+	Amount         decimal.Decimal
+	Address        string
+	Opened         string
+	Authorized     bool
+	PendingPayment bool
+	TxCost         decimal.Decimal
+	TxID           *string `json:"TxId"`
+	Canceled       bool
+	InvalidAddress bool
+}
+
+// Deposit ...
+type Deposit struct {
+	ID            int64 `json:"Id"`
+	Amount        decimal.Decimal
+	Currency      string
+	Confirmations int
+	LastUpdated   string
+	TxID          string `json:"TxId"`
+	CryptoAddress string
 }
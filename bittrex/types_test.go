@@ -0,0 +1,89 @@
+package bittrex
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTickerDecoding(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		bid  string
+		ask  string
+		last string
+	}{
+		{
+			name: "numbers",
+			data: `{"Bid":0.01234567,"Ask":0.01234568,"Last":0.01234569}`,
+			bid:  "0.01234567",
+			ask:  "0.01234568",
+			last: "0.01234569",
+		},
+		{
+			name: "precision beyond float64",
+			data: `{"Bid":0.12345678901234567890,"Ask":1,"Last":1}`,
+			bid:  "0.1234567890123456789",
+			ask:  "1",
+			last: "1",
+		},
+		{
+			name: "null bid and ask",
+			data: `{"Bid":null,"Ask":null,"Last":0.5}`,
+			bid:  "0",
+			ask:  "0",
+			last: "0.5",
+		},
+		{
+			name: "missing fields",
+			data: `{}`,
+			bid:  "0",
+			ask:  "0",
+			last: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticker := new(Ticker)
+			if err := json.Unmarshal([]byte(tt.data), ticker); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+
+			assertDecimal(t, "bid", ticker.Bid, tt.bid)
+			assertDecimal(t, "ask", ticker.Ask, tt.ask)
+			assertDecimal(t, "last", ticker.Last, tt.last)
+		})
+	}
+}
+
+func TestMarketSummaryNullQuotes(t *testing.T) {
+	data := `{"success":true,"message":"","result":[{"MarketName":"BTC-NEW","Bid":null,"Ask":null,"Last":null,"TimeStamp":"2017-07-01T12:00:00.123"}]}`
+
+	response := new(GetMarketSummariesResponse)
+	if err := json.Unmarshal([]byte(data), response); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+
+	if len(response.Result) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(response.Result))
+	}
+	summary := response.Result[0]
+	assertDecimal(t, "bid", summary.Bid, "0")
+	assertDecimal(t, "ask", summary.Ask, "0")
+	assertDecimal(t, "last", summary.Last, "0")
+}
+
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+
+	expected, err := decimal.NewFromString(want)
+	if err != nil {
+		t.Fatalf("invalid expected %s %q: %v", name, want, err)
+	}
+	if !got.Equal(expected) {
+		t.Errorf("%s = %s, want %s", name, got, expected)
+	}
+}
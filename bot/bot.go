@@ -1,44 +1,199 @@
 package bot
 
 import (
+	"fmt"
 	"log"
 	"sync"
+	"time"
 
+	"github.com/RichardKnop/arbitrage/fees"
 	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
 )
 
 // Bot ...
 type Bot struct {
-	Exchanges []types.Exchange
-	Tickers   map[string]map[string]*types.Ticker
-	quit      chan int
-	wg        *sync.WaitGroup
+	Exchanges  []types.Exchange
+	Tickers    *TickerStore
+	OrderBooks *OrderBookStore
+	Balances   *BalanceStore
+	Currencies *CurrencyStore
+	Rules      *RulesStore
+	Risk       *RiskManager
+	Rebalancer *Rebalancer
+	cnf        *Config
+	spread     *SpreadDetector
+	triangular *TriangularDetector
+	cycles     *CycleDetector
+	executor   *Executor
+	quit       chan int
+	quitOnce   *sync.Once
+	done       chan int
+	doneMu     *sync.Mutex // orders closing of done and starting of executions
+	wg         *sync.WaitGroup
 }
 
 // New returns new Bot instance
-func New(exchanges ...types.Exchange) *Bot {
-	return &Bot{
-		Exchanges: exchanges,
-		Tickers:   make(map[string]map[string]*types.Ticker),
-		quit:      make(chan int),
-		wg:        new(sync.WaitGroup),
+func New(cnf *Config, exchanges ...types.Exchange) *Bot {
+	if cnf.Fees == nil {
+		cnf.Fees = fees.New(new(fees.Config))
 	}
+
+	b := &Bot{
+		Exchanges:  exchanges,
+		Tickers:    NewTickerStore(),
+		OrderBooks: NewOrderBookStore(),
+		Balances:   NewBalanceStore(),
+		Currencies: NewCurrencyStore(),
+		Rules:      NewRulesStore(),
+		cnf:        cnf,
+		quit:       make(chan int),
+		quitOnce:   new(sync.Once),
+		done:       make(chan int),
+		doneMu:     new(sync.Mutex),
+		wg:         new(sync.WaitGroup),
+	}
+
+	if cnf.Spread != nil {
+		b.spread = NewSpreadDetector(cnf.Spread, cnf.Fees, b.Tickers, b.OrderBooks, b.Balances, b.Rules)
+	}
+
+	if cnf.Triangular != nil {
+		b.triangular = NewTriangularDetector(cnf.Triangular, cnf.Fees, b.Tickers)
+	}
+
+	if cnf.Cycles != nil {
+		b.cycles = NewCycleDetector(cnf.Cycles, cnf.Fees)
+	}
+
+	if cnf.Execution != nil {
+		riskCnf := cnf.Risk
+		if riskCnf == nil {
+			riskCnf = new(RiskConfig)
+		}
+		b.Risk = NewRiskManager(riskCnf)
+		b.executor = NewExecutor(cnf.Execution, b.Traders(), b.Tickers, b.Rules, b.Risk, cnf.Journal)
+	}
+
+	if cnf.Rebalance != nil {
+		b.Rebalancer = NewRebalancer(cnf.Rebalance, cnf.Fees, b.Balances, b.Currencies, b.Tickers, b.withdrawers())
+	}
+
+	return b
+}
+
+// Traders returns exchanges supporting trading keyed by exchange name, others are used read-only
+func (b *Bot) Traders() map[string]types.Trader {
+	traders := make(map[string]types.Trader)
+	for _, e := range b.Exchanges {
+		if trader, ok := e.(types.Trader); ok {
+			traders[e.GetName()] = trader
+		}
+	}
+
+	return traders
+}
+
+// withdrawers returns exchanges we can move funds between keyed by exchange name
+func (b *Bot) withdrawers() map[string]types.Withdrawer {
+	withdrawers := make(map[string]types.Withdrawer)
+	for _, e := range b.Exchanges {
+		if withdrawer, ok := e.(types.Withdrawer); ok {
+			withdrawers[e.GetName()] = withdrawer
+		}
+	}
+
+	return withdrawers
 }
 
 // Run ...
 func (b *Bot) Run() error {
-	tickers := make(chan *types.Ticker)
+	tickers := make(chan *types.Ticker, tickerBufferSize)
+	books := make(chan *types.OrderBook)
 	errChan := make(chan error)
 
 	for _, e := range b.Exchanges {
-		go func() {
-			b.wg.Add(1)
+		b.wg.Add(1)
+
+		go func(e types.Exchange) {
+			defer b.wg.Done()
 
 			if err := e.Run(tickers); err != nil {
 				log.Print(err)
 			}
+		}(e)
+
+		// Some exchanges can also stream order book snapshots
+		if streamer, ok := e.(types.OrderBookStreamer); ok {
+			b.wg.Add(1)
+
+			go func(streamer types.OrderBookStreamer) {
+				defer b.wg.Done()
+
+				if err := streamer.RunOrderBooks(books); err != nil {
+					log.Print(err)
+				}
+			}(streamer)
+		}
+
+		// Withdrawal fees and confirmations are needed for profitability and rebalancing
+		if provider, ok := e.(types.CurrencyProvider); ok {
+			b.wg.Add(1)
+
+			go func(name string, provider types.CurrencyProvider) {
+				defer b.wg.Done()
+
+				b.refreshCurrencies(name, provider)
+			}(e.GetName(), provider)
+		}
+
+		// Orders are rounded and validated against trading rules before they are sent
+		if provider, ok := e.(types.RulesProvider); ok {
+			b.wg.Add(1)
+
+			go func(name string, provider types.RulesProvider) {
+				defer b.wg.Done()
+
+				b.refreshRules(name, provider)
+			}(e.GetName(), provider)
+		}
+
+		// Keep balances fresh so opportunities can be sized by what we can spend,
+		// exchanges without credentials would only fail every refresh
+		if authenticator, ok := e.(types.Authenticator); ok && !authenticator.HasCredentials() {
+			log.Printf("[%s] Balances are not refreshed without API credentials", e.GetName())
+		} else if provider, ok := e.(types.BalanceProvider); ok && b.cnf.BalanceInterval > 0 {
+			b.wg.Add(1)
+
+			go func(name string, provider types.BalanceProvider) {
+				defer b.wg.Done()
+
+				b.refreshBalances(name, provider)
+			}(e.GetName(), provider)
+		}
+	}
+
+	// Orders journaled before a restart are resumed or unwound
+	if b.executor != nil {
+		b.wg.Add(1)
+
+		go func() {
+			defer b.wg.Done()
+
+			for _, execution := range b.executor.Reconcile() {
+				b.Risk.Complete(nil, execution)
+				b.handleExecution(execution)
+			}
+		}()
+	}
 
-			b.wg.Done()
+	if b.Rebalancer != nil && b.cnf.Rebalance.Interval > 0 {
+		b.wg.Add(1)
+
+		go func() {
+			defer b.wg.Done()
+
+			b.rebalance()
 		}()
 	}
 
@@ -46,10 +201,28 @@ func (b *Bot) Run() error {
 		for {
 			select {
 			case ticker := <-tickers:
-				log.Print(ticker)
+				receivedAt := ticker.ReceivedAt
+				if receivedAt.IsZero() {
+					receivedAt = time.Now()
+				}
+				tickersReceived.Inc(ticker.Exchange)
+				quoteAge.Observe(receivedAt.Sub(ticker.Time).Seconds(), ticker.Exchange)
+
+				b.record(ticker, nil, receivedAt)
+				b.handleTicker(ticker)
+
+				// Search for cycles once the burst of tickers has been processed
+				if b.cycles != nil && len(tickers) == 0 {
+					for _, o := range b.cycles.Search() {
+						b.handleCycleOpportunity(o)
+					}
+				}
+			case book := <-books:
+				b.record(nil, book, time.Now())
+				b.OrderBooks.Update(book)
 			case <-b.quit:
 				errChan <- nil
-			default:
+				return
 			}
 		}
 	}()
@@ -57,18 +230,286 @@ func (b *Bot) Run() error {
 	return <-errChan
 }
 
-// Quit ...
+// Quit stops the bot, it is safe to call more than once, e.g. from a signal
+// handler while replay is finishing
 func (b *Bot) Quit() {
-	// Trigger graceful shutdown of all exchange processes
-	for _, e := range b.Exchanges {
-		if err := e.Quit(); err != nil {
-			log.Print(err)
+	b.quitOnce.Do(func() {
+		// Trigger graceful shutdown of all exchange processes
+		for _, e := range b.Exchanges {
+			if err := e.Quit(); err != nil {
+				log.Print(err)
+			}
+		}
+
+		// Stop background loops of the bot itself, no execution starts after this
+		b.doneMu.Lock()
+		close(b.done)
+		b.doneMu.Unlock()
+
+		// Wait for quit process of exchanges to complete
+		log.Print("Waiting for all exchanges to quit gracefully ")
+		b.wg.Wait()
+
+		b.quit <- 1
+	})
+}
+
+func (b *Bot) refreshBalances(name string, provider types.BalanceProvider) {
+	for {
+		balances, err := provider.FetchBalances()
+		if err != nil {
+			log.Printf("[%s] Fetch balances error: %v", name, err)
+		} else {
+			b.Balances.Update(name, balances)
+			b.saveBalances(name, balances, time.Now())
+		}
+
+		select {
+		case <-b.done:
+			return
+		case <-time.After(b.cnf.BalanceInterval):
+		}
+	}
+}
+
+func (b *Bot) refreshRules(name string, provider types.RulesProvider) {
+	for {
+		rules, err := provider.FetchTradingRules()
+		if err != nil {
+			log.Printf("[%s] Fetch trading rules error: %v", name, err)
+		} else {
+			b.Rules.Update(name, rules)
+		}
+
+		if b.cnf.RulesInterval <= 0 {
+			return
+		}
+
+		select {
+		case <-b.done:
+			return
+		case <-time.After(b.cnf.RulesInterval):
+		}
+	}
+}
+
+func (b *Bot) refreshCurrencies(name string, provider types.CurrencyProvider) {
+	currencies, err := provider.FetchCurrencies()
+	if err != nil {
+		log.Printf("[%s] Fetch currencies error: %v", name, err)
+		return
+	}
+
+	b.Currencies.Update(name, currencies)
+
+	withdrawal := make(map[string]decimal.Decimal, len(currencies))
+	for _, c := range currencies {
+		withdrawal[c.Currency] = c.WithdrawalFee
+	}
+	b.cnf.Fees.SetWithdrawalFees(name, withdrawal)
+}
+
+func (b *Bot) rebalance() {
+	var proposed map[string]bool
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-time.After(b.cnf.Rebalance.Interval):
+		}
+
+		latest := make(map[string]bool)
+		for _, t := range b.Rebalancer.Rebalance() {
+			// Proposals unchanged since the last check were saved and logged already
+			key := t.proposalKey()
+			latest[key] = true
+			if t.Status == TransferProposed && proposed[key] {
+				continue
+			}
+
+			b.saveTransfer(t)
+			log.Printf(
+				"Transfer %s %s (%s %s) from %s to %s, fee: %s bps, confirmations: %d, status: %s %s",
+				t.Amount,
+				t.Currency,
+				t.AssetAmount,
+				t.Asset,
+				t.From,
+				t.To,
+				t.FeeBps.StringFixed(2),
+				t.Confirmations,
+				t.Status,
+				t.Error,
+			)
+		}
+		proposed = latest
+	}
+}
+
+// record writes market data as received, before any processing
+func (b *Bot) record(ticker *types.Ticker, book *types.OrderBook, receivedAt time.Time) {
+	if b.cnf.Recorder == nil {
+		return
+	}
+
+	var err error
+	if ticker != nil {
+		err = b.cnf.Recorder.RecordTicker(ticker, receivedAt)
+	} else {
+		err = b.cnf.Recorder.RecordOrderBook(book, receivedAt)
+	}
+	if err != nil {
+		log.Printf("Record market data error: %v", err)
+	}
+}
+
+func (b *Bot) handleTicker(ticker *types.Ticker) {
+	if b.cnf.Verbose {
+		log.Printf(
+			"[%s] %s (%s) bid: %s, ask: %s, last: %s",
+			ticker.Exchange,
+			ticker.Pair,
+			ticker.Market,
+			ticker.Bid,
+			ticker.Ask,
+			ticker.Last,
+		)
+	}
+
+	b.Tickers.Update(ticker)
+
+	if b.spread != nil {
+		for _, o := range b.spread.Detect(ticker) {
+			b.handleOpportunity(o)
 		}
 	}
 
-	// Wait for quit process of exchanges to complete
-	log.Print("Waiting for all exchanges to quit gracefully ")
-	b.wg.Wait()
+	if b.triangular != nil {
+		for _, o := range b.triangular.Detect(ticker) {
+			b.handleTriangularOpportunity(o)
+		}
+	}
+
+	if b.cycles != nil {
+		b.cycles.Update(ticker)
+	}
+}
+
+func (b *Bot) handleOpportunity(o *Opportunity) {
+	log.Printf(
+		"Opportunity %s: buy on %s at %s, sell on %s at %s, gross: %s bps, net: %s bps, withdrawal cost: %s %s",
+		o.Pair,
+		o.BuyExchange,
+		o.BuyPrice,
+		o.SellExchange,
+		o.SellPrice,
+		o.GrossSpreadBps.StringFixed(2),
+		o.NetSpreadBps.StringFixed(2),
+		o.WithdrawalCost,
+		o.Pair.Quote,
+	)
+	observeOpportunity("spread", o.NetSpreadBps)
+	b.saveOpportunity(o)
+
+	if b.executor == nil || o.Quantity.Sign() <= 0 {
+		return
+	}
+
+	// Quit waits for executions in flight, new ones are not started while quitting
+	if !b.startExecution() {
+		return
+	}
+
+	// Both legs must pass pre-trade risk checks
+	reservation, err := b.Risk.Check(o, o.Quantity, 2)
+	if err != nil {
+		log.Print(err)
+		b.wg.Done()
+		return
+	}
+
+	// Execution blocks until all orders are closed, do not hold up the ticker loop
+	go func() {
+		defer b.wg.Done()
+
+		execution := b.executor.Execute(o, o.Quantity)
+		b.Risk.Complete(reservation, execution)
+		b.handleExecution(execution)
+	}()
+}
+
+// startExecution adds an execution to the wait group unless the bot is quitting
+func (b *Bot) startExecution() bool {
+	b.doneMu.Lock()
+	defer b.doneMu.Unlock()
+
+	select {
+	case <-b.done:
+		return false
+	default:
+	}
+
+	b.wg.Add(1)
+	return true
+}
+
+func (b *Bot) handleExecution(execution *Execution) {
+	b.saveExecution(execution)
+
+	orders := append([]*types.Order{execution.BuyOrder, execution.SellOrder}, execution.HedgeOrders...)
+	for _, order := range orders {
+		if order != nil {
+			ordersClosed.Inc(order.Exchange, string(order.Status))
+		}
+	}
+
+	o := execution.Opportunity
+	log.Printf(
+		"Execution %s %s (%s -> %s) %s: quantity: %s, residual: %s, realized P&L: %s %s, unrealized P&L: %s %s",
+		execution.ID,
+		o.Pair,
+		o.BuyExchange,
+		o.SellExchange,
+		execution.Status,
+		execution.Quantity,
+		execution.Residual,
+		execution.RealizedPnL,
+		o.Pair.Quote,
+		execution.UnrealizedPnL,
+		o.Pair.Quote,
+	)
+
+	for _, err := range execution.Errors {
+		log.Printf("Execution %s error: %v", o.Pair, err)
+	}
+}
+
+func (b *Bot) handleTriangularOpportunity(o *TriangularOpportunity) {
+	log.Printf(
+		"[%s] Triangular opportunity %s, gross: %s bps, net: %s bps",
+		o.Exchange,
+		formatLegs(o.Legs),
+		o.GrossReturnBps.StringFixed(2),
+		o.NetReturnBps.StringFixed(2),
+	)
+	observeOpportunity("triangular", o.NetReturnBps)
+}
+
+func (b *Bot) handleCycleOpportunity(o *CycleOpportunity) {
+	log.Printf(
+		"Cycle opportunity %s, gross: %s bps, net: %s bps",
+		formatLegs(o.Legs),
+		o.GrossReturnBps.StringFixed(2),
+		o.NetReturnBps.StringFixed(2),
+	)
+	observeOpportunity("cycle", o.NetReturnBps)
+}
+
+func formatLegs(legs []*Leg) string {
+	path := legs[0].From
+	for _, leg := range legs {
+		path += fmt.Sprintf(" -(%s %s %s at %s)-> %s", leg.Exchange, leg.Side, leg.Pair, leg.Price, leg.To)
+	}
 
-	b.quit <- 1
+	return path
 }
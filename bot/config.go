@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/journal"
+	"github.com/RichardKnop/arbitrage/recorder"
+	"github.com/RichardKnop/arbitrage/storage"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// DefaultMaxQuoteAge ...
+	DefaultMaxQuoteAge = 30 * time.Second
+	// DefaultMaxCycleLegs ...
+	DefaultMaxCycleLegs = 5
+	// DefaultCycleSearchBudget ...
+	DefaultCycleSearchBudget = 50 * time.Millisecond
+	// DefaultBalanceInterval ...
+	DefaultBalanceInterval = 30 * time.Second
+	// DefaultFillTimeout ...
+	DefaultFillTimeout = 10 * time.Second
+	// DefaultOrderPollInterval ...
+	DefaultOrderPollInterval = time.Second
+	// DefaultCancelTimeout ...
+	DefaultCancelTimeout = 30 * time.Second
+	// DefaultRebalanceInterval ...
+	DefaultRebalanceInterval = 5 * time.Minute
+	// DefaultTransferTimeout ...
+	DefaultTransferTimeout = 2 * time.Hour
+	// DefaultRulesInterval ...
+	DefaultRulesInterval = time.Hour
+	// DefaultMaxOrdersPerMinute ...
+	DefaultMaxOrdersPerMinute = 20
+	// tickerBufferSize lets exchanges push a burst of tickers before the bot processes them
+	tickerBufferSize = 100
+	// maxCancelBackoff caps the delay between retries of a failed cancel
+	maxCancelBackoff = 8 * time.Second
+	// clockSkew tolerated between our clock and exchange timestamps when matching orders
+	clockSkew = time.Minute
+)
+
+var (
+	// DefaultMinNetSpreadBps ...
+	DefaultMinNetSpreadBps = decimal.New(10, 0)
+	// DefaultMinReturnBps ...
+	DefaultMinReturnBps = decimal.New(10, 0)
+	// DefaultMaxSlippageBps ...
+	DefaultMaxSlippageBps = decimal.New(50, 0)
+)
+
+// Config stores bot configuration options
+type Config struct {
+	Fees       *fees.Model        // trading and withdrawal fees used in all profitability calculations
+	Spread     *SpreadConfig      // cross-exchange spread detector, disabled when nil
+	Triangular *TriangularConfig  // single exchange triangular detector, disabled when nil
+	Cycles     *CycleConfig       // multi-leg cycle detector across all exchanges, disabled when nil
+	Execution  *ExecutorConfig    // trades spread opportunities, bot only logs them when nil
+	Risk       *RiskConfig        // pre-trade limits, no limits apply when nil
+	Rebalance  *RebalanceConfig   // inventory rebalancing between exchanges, disabled when nil
+	Journal    *journal.Journal   // durable record of sent orders, orders are not persisted when nil
+	Recorder   *recorder.Recorder // captures tickers and order books, nothing is recorded when nil
+	Storage    *storage.DB        // history of opportunities, orders, balances and transfers, not kept when nil
+	// BalanceInterval is how often balances are refreshed from exchanges able to report them
+	BalanceInterval time.Duration
+	// RulesInterval is how often trading rules are refreshed, zero means they are fetched only once
+	RulesInterval time.Duration
+	// Verbose logs every ticker received, opportunities and executions are always logged
+	Verbose bool
+}
@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// CycleConfig stores multi-leg cycle detector options
+type CycleConfig struct {
+	MaxLegs      int             // longest cycle we search for
+	MinReturnBps decimal.Decimal // opportunities with lower net return are ignored
+	MaxQuoteAge  time.Duration   // older quotes are considered stale, zero means no limit
+	SearchBudget time.Duration   // maximum time spent searching after a burst of ticks, zero means no limit
+}
+
+// CycleOpportunity is a profitable cycle of arbitrary length across any venues
+type CycleOpportunity struct {
+	Legs           []*Leg
+	GrossReturnBps decimal.Decimal
+	NetReturnBps   decimal.Decimal
+	DetectedAt     time.Time
+}
+
+// cycleEdge converts one currency into another, weight is -log of the rate after fees
+type cycleEdge struct {
+	from   int
+	to     int
+	weight float64
+	leg    *Leg
+}
+
+type cycleEdgeKey struct {
+	exchange string
+	pair     types.Pair
+	side     types.Side
+}
+
+// CycleDetector treats every quote as a pair of weighted edges between currencies
+// and finds negative weight cycles, i.e. sequences of trades with positive return
+type CycleDetector struct {
+	cnf        *CycleConfig
+	fees       *fees.Model
+	currencies []string
+	index      map[string]int
+	edges      map[cycleEdgeKey]*cycleEdge
+	nextSource int
+	dirty      bool
+}
+
+// NewCycleDetector returns new CycleDetector instance
+func NewCycleDetector(cnf *CycleConfig, feeModel *fees.Model) *CycleDetector {
+	return &CycleDetector{
+		cnf:   cnf,
+		fees:  feeModel,
+		index: make(map[string]int),
+		edges: make(map[cycleEdgeKey]*cycleEdge),
+	}
+}
+
+// Update replaces both edges of the ticker's market with the latest prices
+func (d *CycleDetector) Update(ticker *types.Ticker) {
+	fee := one.Sub(d.fees.Taker(ticker.Exchange, ticker.Pair))
+	d.setEdge(newLeg(ticker, ticker.Pair.Quote, ticker.Pair.Base), fee)
+	d.setEdge(newLeg(ticker, ticker.Pair.Base, ticker.Pair.Quote), fee)
+	d.dirty = true
+}
+
+// Search looks for profitable cycles within the configured time budget, sources
+// not reached before the deadline are searched first next time
+func (d *CycleDetector) Search() []*CycleOpportunity {
+	return d.SearchAt(time.Now())
+}
+
+// SearchAt is Search with quote ages measured at the given time, e.g. when replaying
+// recorded data, the time budget is still measured by the wall clock
+func (d *CycleDetector) SearchAt(now time.Time) []*CycleOpportunity {
+	if !d.dirty || len(d.currencies) == 0 {
+		return nil
+	}
+	d.dirty = false
+
+	deadline := time.Now().Add(d.cnf.SearchBudget)
+	edges := d.freshEdges(now)
+	minReturnBps, _ := d.cnf.MinReturnBps.Float64()
+	threshold := -math.Log1p(minReturnBps / 10000)
+
+	var (
+		opportunities []*CycleOpportunity
+		seen          = make(map[string]bool)
+	)
+	for i := 0; i < len(d.currencies); i++ {
+		if d.cnf.SearchBudget > 0 && time.Now().After(deadline) {
+			d.dirty = true
+			break
+		}
+
+		source := d.nextSource
+		d.nextSource = (d.nextSource + 1) % len(d.currencies)
+
+		for _, cycle := range d.searchFrom(source, edges, threshold) {
+			key := cycleKey(cycle)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if o := newCycleOpportunity(cycle, d.fees, now); o.NetReturnBps.GreaterThanOrEqual(d.cnf.MinReturnBps) {
+				opportunities = append(opportunities, o)
+			}
+		}
+	}
+
+	return opportunities
+}
+
+// searchFrom runs Bellman-Ford bounded to MaxLegs iterations, keeping distances per
+// iteration so cycles returning to the source can be reconstructed leg by leg
+func (d *CycleDetector) searchFrom(source int, edges []*cycleEdge, threshold float64) [][]*cycleEdge {
+	n := len(d.currencies)
+	dist := make([][]float64, d.cnf.MaxLegs+1)
+	parent := make([][]*cycleEdge, d.cnf.MaxLegs+1)
+	for k := range dist {
+		dist[k] = make([]float64, n)
+		parent[k] = make([]*cycleEdge, n)
+		for v := range dist[k] {
+			dist[k][v] = math.Inf(1)
+		}
+	}
+	dist[0][source] = 0
+
+	var cycles [][]*cycleEdge
+	for k := 1; k <= d.cnf.MaxLegs; k++ {
+		for _, e := range edges {
+			if math.IsInf(dist[k-1][e.from], 1) {
+				continue
+			}
+			if w := dist[k-1][e.from] + e.weight; w < dist[k][e.to] {
+				dist[k][e.to] = w
+				parent[k][e.to] = e
+			}
+		}
+
+		if k < 2 || dist[k][source] >= threshold {
+			continue
+		}
+
+		// Walk parents back from the source to get the cycle of length k
+		cycle := make([]*cycleEdge, k)
+		v := source
+		for level := k; level > 0; level-- {
+			cycle[level-1] = parent[level][v]
+			v = parent[level][v].from
+		}
+
+		if isSimpleCycle(cycle) {
+			cycles = append(cycles, cycle)
+		}
+	}
+
+	return cycles
+}
+
+func (d *CycleDetector) setEdge(leg *Leg, fee decimal.Decimal) {
+	key := cycleEdgeKey{exchange: leg.Exchange, pair: leg.Pair, side: leg.Side}
+	if leg.Price.Sign() <= 0 {
+		delete(d.edges, key)
+		return
+	}
+
+	rate, _ := leg.Rate().Mul(fee).Float64()
+	d.edges[key] = &cycleEdge{
+		from:   d.currencyIndex(leg.From),
+		to:     d.currencyIndex(leg.To),
+		weight: -math.Log(rate),
+		leg:    leg,
+	}
+}
+
+func (d *CycleDetector) currencyIndex(currency string) int {
+	if i, ok := d.index[currency]; ok {
+		return i
+	}
+
+	d.index[currency] = len(d.currencies)
+	d.currencies = append(d.currencies, currency)
+	return d.index[currency]
+}
+
+func (d *CycleDetector) freshEdges(now time.Time) []*cycleEdge {
+	edges := make([]*cycleEdge, 0, len(d.edges))
+	for _, e := range d.edges {
+		if d.cnf.MaxQuoteAge > 0 && now.Sub(e.leg.Time) > d.cnf.MaxQuoteAge {
+			continue
+		}
+		edges = append(edges, e)
+	}
+
+	// Relax edges in the same order every search so ties between equal rates resolve the same way
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.from != b.from {
+			return a.from < b.from
+		}
+		if a.to != b.to {
+			return a.to < b.to
+		}
+		if a.leg.Exchange != b.leg.Exchange {
+			return a.leg.Exchange < b.leg.Exchange
+		}
+		return a.leg.Pair.String() < b.leg.Pair.String()
+	})
+
+	return edges
+}
+
+func newCycleOpportunity(cycle []*cycleEdge, feeModel *fees.Model, now time.Time) *CycleOpportunity {
+	legs := make([]*Leg, len(cycle))
+	gross, net := one, one
+	for i, e := range cycle {
+		legs[i] = e.leg
+		gross = gross.Mul(e.leg.Rate())
+		net = net.Mul(e.leg.Rate()).Mul(one.Sub(feeModel.Taker(e.leg.Exchange, e.leg.Pair)))
+	}
+
+	return &CycleOpportunity{
+		Legs:           legs,
+		GrossReturnBps: gross.Sub(one).Mul(bpsPerUnit),
+		NetReturnBps:   net.Sub(one).Mul(bpsPerUnit),
+		DetectedAt:     now,
+	}
+}
+
+// isSimpleCycle returns false if the cycle visits any currency more than once
+func isSimpleCycle(cycle []*cycleEdge) bool {
+	visited := make(map[int]bool, len(cycle))
+	for _, e := range cycle {
+		if visited[e.from] {
+			return false
+		}
+		visited[e.from] = true
+	}
+
+	return true
+}
+
+// cycleKey identifies a cycle regardless of which leg it starts with
+func cycleKey(cycle []*cycleEdge) string {
+	keys := make([]string, len(cycle))
+	start := 0
+	for i, e := range cycle {
+		keys[i] = e.leg.Exchange + ":" + e.leg.Pair.String() + ":" + string(e.leg.Side)
+		if keys[i] < keys[start] {
+			start = i
+		}
+	}
+
+	return strings.Join(append(keys[start:], keys[:start]...), ",")
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+func TestCycleSearch(t *testing.T) {
+	now := time.Now()
+	ticker := func(exchange, base, quote, bid, ask string, age time.Duration) *types.Ticker {
+		return &types.Ticker{
+			Exchange: exchange,
+			Pair:     types.Pair{Base: base, Quote: quote},
+			Bid:      mustDecimal(bid),
+			Ask:      mustDecimal(ask),
+			Time:     now.Add(-age),
+		}
+	}
+	triangle := []*types.Ticker{
+		ticker("a", "LTC", "BTC", "0.01", "0.0101", 0),
+		ticker("a", "ETH", "BTC", "0.05", "0.0501", 0),
+		ticker("a", "LTC", "ETH", "0.25", "0.251", 0),
+	}
+	taker := fees.New(&fees.Config{
+		Exchanges: map[string]*fees.Schedule{
+			"a": {Rates: fees.Rates{Taker: mustDecimal("0.0025")}},
+			"b": {Rates: fees.Rates{Taker: mustDecimal("0.0025")}},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		tickers  []*types.Ticker
+		fees     *fees.Model
+		maxLegs  int
+		legs     []int    // of each opportunity found
+		grossBps []string // of each opportunity found
+	}{
+		{
+			name: "spread between exchanges",
+			tickers: []*types.Ticker{
+				ticker("a", "LTC", "BTC", "0.01", "0.0101", 0),
+				ticker("b", "LTC", "BTC", "0.011", "0.0111", 0),
+			},
+			maxLegs:  5,
+			legs:     []int{2},
+			grossBps: []string{"891.09"},
+		},
+		{
+			name:     "triangle on one exchange",
+			tickers:  triangle,
+			maxLegs:  5,
+			legs:     []int{3},
+			grossBps: []string{"2376.24"},
+		},
+		{
+			name:    "cycle longer than max legs",
+			tickers: triangle,
+			maxLegs: 2,
+		},
+		{
+			name: "no arbitrage",
+			tickers: []*types.Ticker{
+				ticker("a", "LTC", "BTC", "0.01", "0.0101", 0),
+				ticker("b", "LTC", "BTC", "0.01", "0.0101", 0),
+				ticker("a", "ETH", "BTC", "0.05", "0.0501", 0),
+				ticker("a", "LTC", "ETH", "0.2", "0.2", 0),
+			},
+			maxLegs: 5,
+		},
+		{
+			name: "fees above spread",
+			tickers: []*types.Ticker{
+				ticker("a", "LTC", "BTC", "0.01", "0.0101", 0),
+				ticker("b", "LTC", "BTC", "0.01015", "0.0103", 0),
+			},
+			fees:    taker,
+			maxLegs: 5,
+		},
+		{
+			name: "stale quote",
+			tickers: []*types.Ticker{
+				ticker("a", "LTC", "BTC", "0.01", "0.0101", 2*time.Minute),
+				ticker("b", "LTC", "BTC", "0.011", "0.0111", 0),
+			},
+			maxLegs: 5,
+		},
+		{
+			name: "quote without bid",
+			tickers: []*types.Ticker{
+				ticker("a", "LTC", "BTC", "0.01", "0.0101", 0),
+				ticker("b", "LTC", "BTC", "0", "0.0111", 0),
+			},
+			maxLegs: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feeModel := tt.fees
+			if feeModel == nil {
+				feeModel = fees.New(&fees.Config{
+					Exchanges: map[string]*fees.Schedule{"a": {}, "b": {}},
+				})
+			}
+			d := NewCycleDetector(&CycleConfig{
+				MaxLegs:      tt.maxLegs,
+				MinReturnBps: decimal.New(10, 0),
+				MaxQuoteAge:  time.Minute,
+			}, feeModel)
+			for _, ticker := range tt.tickers {
+				d.Update(ticker)
+			}
+
+			opportunities := d.SearchAt(now)
+			if len(opportunities) != len(tt.legs) {
+				t.Fatalf("got %d opportunities, want %d", len(opportunities), len(tt.legs))
+			}
+			for i, o := range opportunities {
+				if len(o.Legs) != tt.legs[i] {
+					t.Errorf("opportunity %d has %d legs, want %d", i, len(o.Legs), tt.legs[i])
+				}
+				if gross := o.GrossReturnBps.StringFixed(2); gross != tt.grossBps[i] {
+					t.Errorf("opportunity %d gross return = %s bps, want %s", i, gross, tt.grossBps[i])
+				}
+
+				// Each leg gives what the previous one got
+				for j, leg := range o.Legs {
+					next := o.Legs[(j+1)%len(o.Legs)]
+					if leg.To != next.From {
+						t.Errorf("opportunity %d leg %d ends in %s, next starts with %s", i, j, leg.To, next.From)
+					}
+				}
+			}
+
+			// Nothing changed since the last search
+			if again := d.SearchAt(now); again != nil {
+				t.Errorf("got %d opportunities without updates", len(again))
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// ExecutableQuantity walks asks of the buy side and bids of the sell side and returns
+// the largest quantity which can be bought and sold with every unit still at a positive
+// spread after fees
+func ExecutableQuantity(asks, bids []*types.PriceLevel, buyFee, sellFee decimal.Decimal) decimal.Decimal {
+	var (
+		quantity                   = decimal.Zero
+		i, j                       int
+		askRemaining, bidRemaining decimal.Decimal
+	)
+
+	if len(asks) > 0 {
+		askRemaining = asks[0].Quantity
+	}
+	if len(bids) > 0 {
+		bidRemaining = bids[0].Quantity
+	}
+
+	for i < len(asks) && j < len(bids) {
+		cost := asks[i].Price.Mul(one.Add(buyFee))
+		proceeds := bids[j].Price.Mul(one.Sub(sellFee))
+		if !proceeds.GreaterThan(cost) {
+			break
+		}
+
+		// Consume the smaller of the two levels
+		matched := decimal.Min(askRemaining, bidRemaining)
+		quantity = quantity.Add(matched)
+		askRemaining = askRemaining.Sub(matched)
+		bidRemaining = bidRemaining.Sub(matched)
+
+		if askRemaining.Sign() <= 0 {
+			i++
+			if i < len(asks) {
+				askRemaining = asks[i].Quantity
+			}
+		}
+		if bidRemaining.Sign() <= 0 {
+			j++
+			if j < len(bids) {
+				bidRemaining = bids[j].Quantity
+			}
+		}
+	}
+
+	return quantity
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// levels builds price levels from price and quantity pairs
+func levels(values ...string) []*types.PriceLevel {
+	result := make([]*types.PriceLevel, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		result = append(result, &types.PriceLevel{Price: mustDecimal(values[i]), Quantity: mustDecimal(values[i+1])})
+	}
+	return result
+}
+
+func TestExecutableQuantity(t *testing.T) {
+	tests := []struct {
+		name     string
+		asks     []*types.PriceLevel
+		bids     []*types.PriceLevel
+		fee      string // of both sides
+		quantity string
+	}{
+		{
+			name:     "empty books",
+			fee:      "0",
+			quantity: "0",
+		},
+		{
+			name:     "no bids",
+			asks:     levels("1", "1"),
+			fee:      "0",
+			quantity: "0",
+		},
+		{
+			name:     "no spread",
+			asks:     levels("1.01", "1"),
+			bids:     levels("1", "1"),
+			fee:      "0",
+			quantity: "0",
+		},
+		{
+			name:     "equal prices",
+			asks:     levels("1", "1"),
+			bids:     levels("1", "1"),
+			fee:      "0",
+			quantity: "0",
+		},
+		{
+			name:     "smaller of the top levels",
+			asks:     levels("1", "2"),
+			bids:     levels("1.01", "0.5"),
+			fee:      "0",
+			quantity: "0.5",
+		},
+		{
+			name:     "walks levels until spread closes",
+			asks:     levels("1", "1", "1.01", "2", "1.05", "5"),
+			bids:     levels("1.04", "1.5", "1.02", "2", "1", "10"),
+			fee:      "0",
+			quantity: "3",
+		},
+		{
+			name:     "fees close the spread earlier",
+			asks:     levels("1", "1", "1.01", "2", "1.05", "5"),
+			bids:     levels("1.04", "1.5", "1.02", "2", "1", "10"),
+			fee:      "0.005",
+			quantity: "1.5",
+		},
+		{
+			name:     "fees above spread",
+			asks:     levels("1", "1"),
+			bids:     levels("1.004", "1"),
+			fee:      "0.0025",
+			quantity: "0",
+		},
+		{
+			name:     "books exhausted",
+			asks:     levels("1", "1", "1.01", "1"),
+			bids:     levels("1.1", "5"),
+			fee:      "0",
+			quantity: "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := mustDecimal(tt.fee)
+			assertDecimal(t, "quantity", ExecutableQuantity(tt.asks, tt.bids, fee, fee), tt.quantity)
+		})
+	}
+}
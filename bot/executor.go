@@ -0,0 +1,695 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/journal"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// ResidualPolicy decides what to do with quantity bought but not sold, or sold but not bought
+type ResidualPolicy string
+
+const (
+	// HoldResidual keeps the residual position as it is
+	HoldResidual ResidualPolicy = "hold"
+	// UnwindResidual reverses the excess fill on the exchange where it happened
+	UnwindResidual ResidualPolicy = "unwind"
+	// RehedgeResidual completes the missing leg on its original exchange at a worse price
+	RehedgeResidual ResidualPolicy = "rehedge"
+)
+
+// ExecutionStatus ...
+type ExecutionStatus string
+
+const (
+	// ExecutionCompleted means both legs were filled for the same quantity
+	ExecutionCompleted ExecutionStatus = "completed"
+	// ExecutionPartial means legs were filled for different quantities, see residual
+	ExecutionPartial ExecutionStatus = "partial"
+	// ExecutionUnwound means legs were filled for different quantities and hedge orders flattened the residual
+	ExecutionUnwound ExecutionStatus = "unwound"
+	// ExecutionSkipped means no order was placed, e.g. prices moved away
+	ExecutionSkipped ExecutionStatus = "skipped"
+	// ExecutionFailed means no quantity was traded, e.g. a leg was rejected and the other cancelled
+	ExecutionFailed ExecutionStatus = "failed"
+)
+
+var (
+	// ErrReadOnlyExchange is returned when one of the exchanges does not support trading
+	ErrReadOnlyExchange = errors.New("Exchange does not support trading")
+	// ErrPriceMoved is returned when latest quotes no longer offer the detected prices
+	ErrPriceMoved = errors.New("Price moved away")
+	// ErrExecutionInProgress is returned when the pair is already being traded
+	ErrExecutionInProgress = errors.New("Execution in progress for the pair")
+	// ErrOrderNotFound is returned when a journaled order was not acknowledged and the exchange does not know it
+	ErrOrderNotFound = errors.New("Order not found on exchange")
+	// ErrOrderStuck is returned when an order is still open once the cancel timeout passes,
+	// e.g. the exchange is unreachable, its last known state is used
+	ErrOrderStuck = errors.New("Order still open after cancel timeout")
+)
+
+// ExecutorConfig stores execution engine options
+type ExecutorConfig struct {
+	FillTimeout    time.Duration   // how long we wait for legs to fill before cancelling them
+	PollInterval   time.Duration   // how often status of open orders is checked
+	CancelTimeout  time.Duration   // how long we keep cancelling an order after the fill timeout before giving up on it
+	Policy         ResidualPolicy  // what to do with unmatched fills
+	MaxSlippageBps decimal.Decimal // how much worse than the latest quote we price unwind and rehedge orders
+}
+
+// Execution reports what happened when trading an opportunity
+type Execution struct {
+	ID          string
+	Opportunity *Opportunity
+	Quantity    decimal.Decimal
+	BuyOrder    *types.Order
+	SellOrder   *types.Order
+	HedgeOrders []*types.Order  // unwind or rehedge orders placed for the residual
+	Residual    decimal.Decimal // base currency bought but not sold when positive, sold but not bought when negative
+	// RealizedPnL is quote currency P&L of quantity both bought and sold, fees included
+	RealizedPnL decimal.Decimal
+	// UnrealizedPnL is the residual marked at the latest quote against its cost, fees included
+	UnrealizedPnL decimal.Decimal
+	Status        ExecutionStatus
+	Errors        []error
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	mu            sync.Mutex
+}
+
+// Executor places buy and sell legs of an opportunity concurrently and handles partial fills
+type Executor struct {
+	cnf      *ExecutorConfig
+	traders  map[string]types.Trader
+	tickers  *TickerStore
+	rules    *RulesStore
+	risk     *RiskManager
+	journal  *journal.Journal
+	inFlight map[types.Pair]bool
+	mu       sync.Mutex
+}
+
+// NewExecutor returns new Executor instance
+func NewExecutor(cnf *ExecutorConfig, traders map[string]types.Trader, tickers *TickerStore, rules *RulesStore, risk *RiskManager, j *journal.Journal) *Executor {
+	return &Executor{
+		cnf:      cnf,
+		traders:  traders,
+		tickers:  tickers,
+		rules:    rules,
+		risk:     risk,
+		journal:  j,
+		inFlight: make(map[types.Pair]bool),
+	}
+}
+
+// Execute trades the opportunity for the quantity, it blocks until all orders are closed
+func (e *Executor) Execute(o *Opportunity, quantity decimal.Decimal) *Execution {
+	execution := &Execution{
+		ID:            journal.NewID(),
+		Opportunity:   o,
+		Quantity:      quantity,
+		Residual:      decimal.Zero,
+		RealizedPnL:   decimal.Zero,
+		UnrealizedPnL: decimal.Zero,
+		StartedAt:     time.Now(),
+	}
+	defer func() {
+		execution.FinishedAt = time.Now()
+	}()
+
+	buyPrice, sellPrice, quantity, err := e.round(o, quantity)
+	if err != nil {
+		execution.Status = ExecutionSkipped
+		execution.Errors = append(execution.Errors, err)
+		return execution
+	}
+	execution.Quantity = quantity
+
+	buyTrader, sellTrader, err := e.prepare(o)
+	if err != nil {
+		execution.Status = ExecutionSkipped
+		execution.Errors = append(execution.Errors, err)
+		return execution
+	}
+	defer e.release(o.Pair)
+
+	// Place both legs at once so prices have as little time as possible to move
+	var (
+		wg                  sync.WaitGroup
+		buyErr, sellErr     error
+		buyOrder, sellOrder *types.Order
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		buyOrder, buyErr = e.place(execution, buyTrader, o.BuyExchange, types.Buy, buyPrice, quantity)
+	}()
+	go func() {
+		defer wg.Done()
+		sellOrder, sellErr = e.place(execution, sellTrader, o.SellExchange, types.Sell, sellPrice, quantity)
+	}()
+	wg.Wait()
+
+	if buyErr != nil {
+		execution.Errors = append(execution.Errors, fmt.Errorf("Buy on %s error: %v", o.BuyExchange, buyErr))
+	}
+	if sellErr != nil {
+		execution.Errors = append(execution.Errors, fmt.Errorf("Sell on %s error: %v", o.SellExchange, sellErr))
+	}
+
+	// If one leg was rejected, cancel the other one straight away
+	timeout := e.cnf.FillTimeout
+	if buyErr != nil || sellErr != nil {
+		timeout = 0
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		execution.BuyOrder, buyErr = e.monitor(buyTrader, buyOrder, timeout, execution)
+	}()
+	go func() {
+		defer wg.Done()
+		execution.SellOrder, sellErr = e.monitor(sellTrader, sellOrder, timeout, execution)
+	}()
+	wg.Wait()
+
+	// Stuck legs are settled with their last known fill
+	for _, err := range []error{buyErr, sellErr} {
+		if err != nil {
+			execution.addError(err)
+		}
+	}
+
+	execution.Residual = filled(execution.BuyOrder).Sub(filled(execution.SellOrder))
+	if execution.Residual.Sign() != 0 {
+		e.handleResidual(execution, e.cnf.Policy, buyTrader, sellTrader)
+	}
+
+	execution.RealizedPnL, execution.UnrealizedPnL = e.pnl(execution)
+	execution.Status = executionStatus(execution)
+
+	// Residual and orders left open are picked up by reconcile after a restart
+	if settled(execution) {
+		e.complete(execution)
+	}
+
+	return execution
+}
+
+// round applies trading rules of both exchanges to the legs so neither of them gets rejected
+func (e *Executor) round(o *Opportunity, quantity decimal.Decimal) (decimal.Decimal, decimal.Decimal, decimal.Decimal, error) {
+	// Both legs trade the same quantity so it has to fit steps of both exchanges
+	quantity = e.rules.RoundQuantity(o.BuyExchange, o.Pair, quantity)
+	quantity = e.rules.RoundQuantity(o.SellExchange, o.Pair, quantity)
+
+	buyPrice := e.rules.RoundPrice(o.BuyExchange, o.Pair, types.Buy, o.BuyPrice)
+	if err := e.rules.Validate(o.BuyExchange, o.Pair, buyPrice, quantity); err != nil {
+		return buyPrice, o.SellPrice, quantity, err
+	}
+
+	sellPrice := e.rules.RoundPrice(o.SellExchange, o.Pair, types.Sell, o.SellPrice)
+	if err := e.rules.Validate(o.SellExchange, o.Pair, sellPrice, quantity); err != nil {
+		return buyPrice, sellPrice, quantity, err
+	}
+
+	return buyPrice, sellPrice, quantity, nil
+}
+
+// prepare looks up traders of both exchanges, checks prices did not move
+// away and marks the pair as being traded
+func (e *Executor) prepare(o *Opportunity) (types.Trader, types.Trader, error) {
+	buyTrader, ok := e.traders[o.BuyExchange]
+	if !ok {
+		return nil, nil, fmt.Errorf("%v: %s", ErrReadOnlyExchange, o.BuyExchange)
+	}
+
+	sellTrader, ok := e.traders[o.SellExchange]
+	if !ok {
+		return nil, nil, fmt.Errorf("%v: %s", ErrReadOnlyExchange, o.SellExchange)
+	}
+
+	if buy, ok := e.tickers.Get(o.BuyExchange, o.Pair); ok && buy.Ask.GreaterThan(o.BuyPrice) {
+		return nil, nil, ErrPriceMoved
+	}
+	if sell, ok := e.tickers.Get(o.SellExchange, o.Pair); ok && sell.Bid.LessThan(o.SellPrice) {
+		return nil, nil, ErrPriceMoved
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.inFlight[o.Pair] {
+		return nil, nil, ErrExecutionInProgress
+	}
+	e.inFlight[o.Pair] = true
+
+	return buyTrader, sellTrader, nil
+}
+
+// Reconcile resumes executions journaled before a restart, their open orders are
+// monitored until the fill timeout passes and residual quantity is unwound
+func (e *Executor) Reconcile() []*Execution {
+	if e.journal == nil {
+		return nil
+	}
+
+	incomplete := e.journal.Incomplete()
+
+	// Keep new executions away from pairs we are still cleaning up
+	e.mu.Lock()
+	pairs := make(map[types.Pair]bool)
+	for _, entries := range incomplete {
+		pair := entries[0].Pair
+		if !e.inFlight[pair] {
+			e.inFlight[pair] = true
+			pairs[pair] = true
+		}
+	}
+	e.mu.Unlock()
+	defer func() {
+		for pair := range pairs {
+			e.release(pair)
+		}
+	}()
+
+	executions := make([]*Execution, 0, len(incomplete))
+	for id, entries := range incomplete {
+		executions = append(executions, e.reconcile(id, entries))
+	}
+
+	return executions
+}
+
+func (e *Executor) reconcile(id string, entries []*journal.Entry) *Execution {
+	o := &Opportunity{Pair: entries[0].Pair}
+	execution := &Execution{
+		ID:            id,
+		Opportunity:   o,
+		Quantity:      entries[0].Quantity,
+		Residual:      decimal.Zero,
+		RealizedPnL:   decimal.Zero,
+		UnrealizedPnL: decimal.Zero,
+		StartedAt:     time.Now(),
+	}
+	defer func() {
+		execution.FinishedAt = time.Now()
+	}()
+
+	// Entries are sorted by creation time so the first order of each side is a leg, the rest are hedges
+	for _, entry := range entries {
+		trader, ok := e.traders[entry.Exchange]
+		if !ok {
+			execution.addError(fmt.Errorf("%v: %s", ErrReadOnlyExchange, entry.Exchange))
+			continue
+		}
+
+		order, err := e.recover(trader, entry)
+		if err != nil {
+			execution.addError(err)
+			continue
+		}
+		if order == nil {
+			continue
+		}
+
+		// Resume waiting for the fill where we left off
+		timeout := e.cnf.FillTimeout - time.Since(entry.CreatedAt)
+		if timeout < 0 {
+			timeout = 0
+		}
+		order, err = e.monitor(trader, order, timeout, execution)
+		if err != nil {
+			execution.addError(err)
+		}
+
+		switch {
+		case order.Side == types.Buy && execution.BuyOrder == nil:
+			execution.BuyOrder, o.BuyExchange = order, entry.Exchange
+		case order.Side == types.Sell && execution.SellOrder == nil:
+			execution.SellOrder, o.SellExchange = order, entry.Exchange
+		default:
+			execution.HedgeOrders = append(execution.HedgeOrders, order)
+		}
+
+		if order.Side == types.Buy {
+			execution.Residual = execution.Residual.Add(filled(order))
+		} else {
+			execution.Residual = execution.Residual.Sub(filled(order))
+		}
+	}
+
+	// Opportunity behind the execution is gone, flatten the position rather than complete it
+	if execution.Residual.Sign() != 0 {
+		exchange := o.BuyExchange
+		if execution.Residual.Sign() < 0 {
+			exchange = o.SellExchange
+		}
+		e.awaitQuote(exchange, o.Pair)
+		e.handleResidual(execution, UnwindResidual, e.traders[o.BuyExchange], e.traders[o.SellExchange])
+	}
+
+	execution.RealizedPnL, execution.UnrealizedPnL = e.pnl(execution)
+	execution.Status = executionStatus(execution)
+
+	// Residual we failed to unwind is retried after the next restart
+	if settled(execution) {
+		e.complete(execution)
+	}
+
+	return execution
+}
+
+// recover returns the current state of a journaled order from the exchange, nil
+// when the order never reached the exchange or was rejected
+func (e *Executor) recover(trader types.Trader, entry *journal.Entry) (*types.Order, error) {
+	if entry.State == journal.StateRejected {
+		return nil, nil
+	}
+
+	if entry.OrderID != "" {
+		order, err := trader.FetchOrder(entry.OrderID)
+		if err != nil {
+			return nil, fmt.Errorf("Fetch order %s on %s error: %v", entry.OrderID, entry.Exchange, err)
+		}
+		if err := e.journal.Update(order); err != nil {
+			return order, fmt.Errorf("Journal order %s error: %v", order.ID, err)
+		}
+		return order, nil
+	}
+
+	// We crashed before the acknowledgement was journaled, look for an open order matching the intent
+	orders, err := trader.FetchOpenOrders(entry.Pair)
+	if err != nil {
+		return nil, fmt.Errorf("Fetch open orders on %s error: %v", entry.Exchange, err)
+	}
+	for _, order := range orders {
+		if order.Side != entry.Side || !order.Price.Equal(entry.Price) || !order.Quantity.Equal(entry.Quantity) {
+			continue
+		}
+		if order.CreatedAt.Before(entry.CreatedAt.Add(-clockSkew)) {
+			continue
+		}
+
+		if err := e.journal.Acknowledge(entry.ClientID, order); err != nil {
+			return order, fmt.Errorf("Journal order %s error: %v", order.ID, err)
+		}
+		return order, nil
+	}
+
+	if err := e.journal.Reject(entry.ClientID, ErrOrderNotFound); err != nil {
+		return nil, fmt.Errorf("Journal order %s error: %v", entry.ClientID, err)
+	}
+	return nil, nil
+}
+
+// awaitQuote waits up to the fill timeout for a ticker, after a restart they take a while to arrive
+func (e *Executor) awaitQuote(exchange string, pair types.Pair) {
+	deadline := time.Now().Add(e.cnf.FillTimeout)
+	for time.Now().Before(deadline) {
+		if _, ok := e.tickers.Get(exchange, pair); ok {
+			return
+		}
+		<-time.After(e.cnf.PollInterval)
+	}
+}
+
+// place journals the intent, sends the order and journals what the exchange replied
+func (e *Executor) place(execution *Execution, trader types.Trader, exchange string, side types.Side, price, quantity decimal.Decimal) (*types.Order, error) {
+	pair := execution.Opportunity.Pair
+	if e.journal == nil {
+		return trader.PlaceOrder(pair, side, price, quantity)
+	}
+
+	// Never send an order we could lose track of
+	intent, err := e.journal.Intent(execution.ID, exchange, pair, side, price, quantity)
+	if err != nil {
+		return nil, fmt.Errorf("Journal intent error: %v", err)
+	}
+
+	order, err := trader.PlaceOrder(pair, side, price, quantity)
+	if err != nil {
+		if journalErr := e.journal.Reject(intent.ClientID, err); journalErr != nil {
+			execution.addError(fmt.Errorf("Journal order %s error: %v", intent.ClientID, journalErr))
+		}
+		return nil, err
+	}
+
+	if err := e.journal.Acknowledge(intent.ClientID, order); err != nil {
+		execution.addError(fmt.Errorf("Journal order %s error: %v", order.ID, err))
+	}
+
+	return order, nil
+}
+
+// complete journals that the execution needs no more attention
+func (e *Executor) complete(execution *Execution) {
+	if e.journal == nil {
+		return
+	}
+
+	if err := e.journal.Complete(execution.ID); err != nil {
+		execution.addError(fmt.Errorf("Journal execution %s error: %v", execution.ID, err))
+	}
+}
+
+func (e *Executor) release(pair types.Pair) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.inFlight, pair)
+}
+
+// monitor polls the order until it is closed and returns its final state. Once the
+// timeout passes the order is cancelled, failed cancels are retried with backoff and
+// when the order is still open after the cancel timeout its last known state is
+// returned with ErrOrderStuck.
+func (e *Executor) monitor(trader types.Trader, order *types.Order, timeout time.Duration, execution *Execution) (*types.Order, error) {
+	if order == nil {
+		return nil, nil
+	}
+
+	cancelAt := time.Now().Add(timeout)
+	deadline := cancelAt.Add(e.cnf.CancelTimeout)
+	backoff := e.cnf.PollInterval
+	cancelled := false
+	for {
+		latest, err := trader.FetchOrder(order.ID)
+		if err != nil {
+			execution.addError(fmt.Errorf("Fetch order %s on %s error: %v", order.ID, order.Exchange, err))
+		} else {
+			order = latest
+			if e.journal != nil {
+				if err := e.journal.Update(order); err != nil {
+					execution.addError(fmt.Errorf("Journal order %s error: %v", order.ID, err))
+				}
+			}
+		}
+
+		if order.IsClosed() {
+			return order, nil
+		}
+
+		now := time.Now()
+		if !now.Before(deadline) {
+			return order, fmt.Errorf("%v: %s on %s", ErrOrderStuck, order.ID, order.Exchange)
+		}
+
+		wait := e.cnf.PollInterval
+		if !cancelled && !now.Before(cancelAt) {
+			if err := trader.CancelOrder(order.ID); err != nil {
+				execution.addError(fmt.Errorf("Cancel order %s on %s error: %v", order.ID, order.Exchange, err))
+				wait, backoff = backoff, backoff*2
+				if backoff > maxCancelBackoff {
+					backoff = maxCancelBackoff
+				}
+			} else {
+				cancelled = true
+			}
+		}
+
+		if untilDeadline := deadline.Sub(now); wait > untilDeadline {
+			wait = untilDeadline
+		}
+		<-time.After(wait)
+	}
+}
+
+// handleResidual unwinds or rehedges unmatched quantity according to the policy
+func (e *Executor) handleResidual(execution *Execution, policy ResidualPolicy, buyTrader, sellTrader types.Trader) {
+	o := execution.Opportunity
+
+	var (
+		trader   types.Trader
+		exchange string
+		side     types.Side
+	)
+	switch {
+	case policy == UnwindResidual && execution.Residual.Sign() > 0:
+		// Sell back what we bought on the buy exchange
+		trader, exchange, side = buyTrader, o.BuyExchange, types.Sell
+	case policy == UnwindResidual:
+		// Buy back what we sold on the sell exchange
+		trader, exchange, side = sellTrader, o.SellExchange, types.Buy
+	case policy == RehedgeResidual && execution.Residual.Sign() > 0:
+		// Sell the rest on the sell exchange even though the bid is lower now
+		trader, exchange, side = sellTrader, o.SellExchange, types.Sell
+	case policy == RehedgeResidual:
+		// Buy the rest on the buy exchange even though the ask is higher now
+		trader, exchange, side = buyTrader, o.BuyExchange, types.Buy
+	default:
+		return
+	}
+
+	ticker, ok := e.tickers.Get(exchange, o.Pair)
+	if !ok {
+		execution.addError(fmt.Errorf("No quote for %s on %s to handle residual", o.Pair, exchange))
+		return
+	}
+
+	// Price aggressively so the hedge crosses the spread
+	slippage := e.cnf.MaxSlippageBps.Div(bpsPerUnit)
+	price := ticker.Bid.Mul(one.Sub(slippage))
+	if side == types.Buy {
+		price = ticker.Ask.Mul(one.Add(slippage))
+	}
+
+	// Residual too small for the exchange to accept is held
+	price = e.rules.RoundPrice(exchange, o.Pair, side, price)
+	quantity := e.rules.RoundQuantity(exchange, o.Pair, execution.Residual.Abs())
+	if err := e.rules.Validate(exchange, o.Pair, price, quantity); err != nil {
+		execution.addError(fmt.Errorf("Hedge %s on %s error: %v", side, exchange, err))
+		return
+	}
+
+	if e.risk != nil {
+		if err := e.risk.CheckHedge(); err != nil {
+			execution.addError(fmt.Errorf("Hedge %s on %s error: %v", side, exchange, err))
+			return
+		}
+	}
+
+	hedge, err := e.place(execution, trader, exchange, side, price, quantity)
+	if err != nil {
+		execution.addError(fmt.Errorf("Hedge %s on %s error: %v", side, exchange, err))
+		return
+	}
+
+	hedge, err = e.monitor(trader, hedge, e.cnf.FillTimeout, execution)
+	if err != nil {
+		execution.addError(err)
+	}
+	execution.HedgeOrders = append(execution.HedgeOrders, hedge)
+
+	if side == types.Sell {
+		execution.Residual = execution.Residual.Sub(filled(hedge))
+	} else {
+		execution.Residual = execution.Residual.Add(filled(hedge))
+	}
+}
+
+func (execution *Execution) addError(err error) {
+	// Legs are monitored concurrently
+	execution.mu.Lock()
+	defer execution.mu.Unlock()
+
+	execution.Errors = append(execution.Errors, err)
+}
+
+func filled(order *types.Order) decimal.Decimal {
+	if order == nil {
+		return decimal.Zero
+	}
+	return order.FilledQuantity
+}
+
+// settled returns true when the execution needs no more attention, all quantity
+// is matched and no order is left open
+func settled(execution *Execution) bool {
+	if execution.Residual.Sign() != 0 {
+		return false
+	}
+
+	for _, order := range append([]*types.Order{execution.BuyOrder, execution.SellOrder}, execution.HedgeOrders...) {
+		if order != nil && !order.IsClosed() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pnl values matched quantity at average prices net of fees and marks the residual at
+// the latest quote of the exchange holding it, at its own cost when there is no quote
+func (e *Executor) pnl(execution *Execution) (decimal.Decimal, decimal.Decimal) {
+	var (
+		bought, sold   = decimal.Zero, decimal.Zero
+		cost, proceeds = decimal.Zero, decimal.Zero
+	)
+	for _, order := range append([]*types.Order{execution.BuyOrder, execution.SellOrder}, execution.HedgeOrders...) {
+		if order == nil || order.FilledQuantity.Sign() <= 0 {
+			continue
+		}
+
+		value := order.FilledQuantity.Mul(order.AveragePrice)
+		if order.Side == types.Buy {
+			bought = bought.Add(order.FilledQuantity)
+			cost = cost.Add(value).Add(order.Fee)
+		} else {
+			sold = sold.Add(order.FilledQuantity)
+			proceeds = proceeds.Sub(order.Fee).Add(value)
+		}
+	}
+
+	// Avoid rounding of average prices when everything was matched
+	if bought.Equal(sold) {
+		return proceeds.Sub(cost), decimal.Zero
+	}
+
+	var unitCost, unitProceeds decimal.Decimal
+	if bought.Sign() > 0 {
+		unitCost = cost.Div(bought)
+	}
+	if sold.Sign() > 0 {
+		unitProceeds = proceeds.Div(sold)
+	}
+
+	matched := decimal.Min(bought, sold)
+	realized := matched.Mul(unitProceeds.Sub(unitCost))
+
+	o := execution.Opportunity
+	residual := bought.Sub(sold)
+	if residual.Sign() > 0 {
+		// Long residual could be sold at the bid where it was bought
+		ticker, ok := e.tickers.Get(o.BuyExchange, o.Pair)
+		if !ok {
+			return realized, decimal.Zero
+		}
+		return realized, residual.Mul(ticker.Bid.Sub(unitCost))
+	}
+
+	// Short residual could be bought back at the ask where it was sold
+	ticker, ok := e.tickers.Get(o.SellExchange, o.Pair)
+	if !ok {
+		return realized, decimal.Zero
+	}
+	return realized, residual.Neg().Mul(unitProceeds.Sub(ticker.Ask))
+}
+
+func executionStatus(execution *Execution) ExecutionStatus {
+	switch {
+	case execution.Residual.Sign() != 0:
+		return ExecutionPartial
+	case filled(execution.BuyOrder).Sign() == 0 && filled(execution.SellOrder).Sign() == 0:
+		return ExecutionFailed
+	case !filled(execution.BuyOrder).Equal(filled(execution.SellOrder)):
+		return ExecutionUnwound
+	default:
+		return ExecutionCompleted
+	}
+}
@@ -0,0 +1,414 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/journal"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var errCancel = errors.New("Cancel failed")
+
+// fills lists fractions of quantity filled on placement by side, one per order,
+// orders beyond the list are filled in full
+type fills map[types.Side][]string
+
+// fakeTrader fills orders as soon as they are placed, quantity left unfilled stays
+// open until the order is cancelled
+type fakeTrader struct {
+	name      string
+	fills     fills
+	cancelErr error
+	orders    map[string]*types.Order
+	nextID    int
+	mu        sync.Mutex
+}
+
+func newFakeTrader(name string, f fills, cancelErr error) *fakeTrader {
+	return &fakeTrader{
+		name:      name,
+		fills:     f,
+		cancelErr: cancelErr,
+		orders:    make(map[string]*types.Order),
+	}
+}
+
+func (t *fakeTrader) PlaceOrder(pair types.Pair, side types.Side, price, quantity decimal.Decimal) (*types.Order, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fraction := one
+	if len(t.fills[side]) > 0 {
+		fraction = mustDecimal(t.fills[side][0])
+		t.fills[side] = t.fills[side][1:]
+	}
+
+	t.nextID++
+	order := &types.Order{
+		ID:             fmt.Sprintf("%s-%d", t.name, t.nextID),
+		Exchange:       t.name,
+		Pair:           pair,
+		Side:           side,
+		Price:          price,
+		Quantity:       quantity,
+		FilledQuantity: quantity.Mul(fraction),
+		AveragePrice:   price,
+		Fee:            decimal.Zero,
+		Status:         types.OrderOpen,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	switch {
+	case order.FilledQuantity.Equal(quantity):
+		order.Status = types.OrderFilled
+	case order.FilledQuantity.Sign() > 0:
+		order.Status = types.OrderPartiallyFilled
+	}
+	t.orders[order.ID] = order
+
+	copied := *order
+	return &copied, nil
+}
+
+func (t *fakeTrader) CancelOrder(id string) error {
+	if t.cancelErr != nil {
+		return t.cancelErr
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.orders[id].Status = types.OrderCancelled
+	return nil
+}
+
+func (t *fakeTrader) FetchOrder(id string) (*types.Order, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	copied := *t.orders[id]
+	return &copied, nil
+}
+
+func (t *fakeTrader) FetchOpenOrders(pair types.Pair) ([]*types.Order, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var orders []*types.Order
+	for _, order := range t.orders {
+		if !order.IsClosed() && (pair == (types.Pair{}) || order.Pair == pair) {
+			copied := *order
+			orders = append(orders, &copied)
+		}
+	}
+
+	return orders, nil
+}
+
+type hedge struct {
+	exchange string
+	side     types.Side
+	quantity string
+}
+
+func TestExecute(t *testing.T) {
+	pair := types.Pair{Base: "LTC", Quote: "BTC"}
+
+	tests := []struct {
+		name          string
+		policy        ResidualPolicy
+		buyFills      fills // of orders on the buy exchange
+		sellFills     fills // of orders on the sell exchange
+		buyCancelErr  error
+		rules         []*types.TradingRules // of the buy exchange
+		maxOrders     int
+		status        ExecutionStatus
+		residual      string
+		hedges        []hedge
+		realizedPnL   string
+		unrealizedPnL string
+		settled       bool
+		errs          []string
+	}{
+		{
+			name:          "both legs filled",
+			policy:        UnwindResidual,
+			status:        ExecutionCompleted,
+			residual:      "0",
+			realizedPnL:   "0.001",
+			unrealizedPnL: "0",
+			settled:       true,
+		},
+		{
+			name:          "nothing filled",
+			policy:        UnwindResidual,
+			buyFills:      fills{types.Buy: {"0"}},
+			sellFills:     fills{types.Sell: {"0"}},
+			status:        ExecutionFailed,
+			residual:      "0",
+			realizedPnL:   "0",
+			unrealizedPnL: "0",
+			settled:       true,
+		},
+		{
+			name:      "long residual unwound on the buy exchange",
+			policy:    UnwindResidual,
+			sellFills: fills{types.Sell: {"0.5"}},
+			status:    ExecutionUnwound,
+			residual:  "0",
+			hedges:    []hedge{{exchange: "buy", side: types.Sell, quantity: "0.5"}},
+			// Half sold at 0.011, half sold back at 0.0099 bid less slippage
+			realizedPnL:   "0.00042525",
+			unrealizedPnL: "0",
+			settled:       true,
+		},
+		{
+			name:          "short residual unwound on the sell exchange",
+			policy:        UnwindResidual,
+			buyFills:      fills{types.Buy: {"0.5"}},
+			status:        ExecutionUnwound,
+			residual:      "0",
+			hedges:        []hedge{{exchange: "sell", side: types.Buy, quantity: "0.5"}},
+			realizedPnL:   "0.00042225",
+			unrealizedPnL: "0",
+			settled:       true,
+		},
+		{
+			name:          "long residual rehedged on the sell exchange",
+			policy:        RehedgeResidual,
+			sellFills:     fills{types.Sell: {"0.5"}},
+			status:        ExecutionUnwound,
+			residual:      "0",
+			hedges:        []hedge{{exchange: "sell", side: types.Sell, quantity: "0.5"}},
+			realizedPnL:   "0.0009725",
+			unrealizedPnL: "0",
+			settled:       true,
+		},
+		{
+			name:          "partially filled rehedge leaves residual",
+			policy:        RehedgeResidual,
+			sellFills:     fills{types.Sell: {"0.6", "0.5"}},
+			status:        ExecutionPartial,
+			residual:      "0.2",
+			hedges:        []hedge{{exchange: "sell", side: types.Sell, quantity: "0.4"}},
+			realizedPnL:   "0.000789",
+			unrealizedPnL: "-0.00002",
+			settled:       false,
+		},
+		{
+			name:      "residual held",
+			policy:    HoldResidual,
+			sellFills: fills{types.Sell: {"0.5"}},
+			status:    ExecutionPartial,
+			residual:  "0.5",
+			// Residual is marked at the 0.0099 bid of the buy exchange
+			realizedPnL:   "0.0005",
+			unrealizedPnL: "-0.00005",
+			settled:       false,
+		},
+		{
+			name:          "residual below minimum quantity held",
+			policy:        UnwindResidual,
+			sellFills:     fills{types.Sell: {"0.5"}},
+			rules:         []*types.TradingRules{{Exchange: "buy", Pair: pair, MinQuantity: mustDecimal("0.6"), Active: true}},
+			status:        ExecutionPartial,
+			residual:      "0.5",
+			realizedPnL:   "0.0005",
+			unrealizedPnL: "-0.00005",
+			settled:       false,
+			errs:          []string{"Hedge sell on buy error"},
+		},
+		{
+			name:          "hedge over order rate held",
+			policy:        UnwindResidual,
+			sellFills:     fills{types.Sell: {"0.5"}},
+			maxOrders:     2,
+			status:        ExecutionPartial,
+			residual:      "0.5",
+			realizedPnL:   "0.0005",
+			unrealizedPnL: "-0.00005",
+			settled:       false,
+			errs:          []string{string(RejectOrderRate)},
+		},
+		{
+			name:          "stuck leg left to reconcile",
+			policy:        UnwindResidual,
+			buyFills:      fills{types.Buy: {"0.5"}},
+			sellFills:     fills{types.Sell: {"0.5"}},
+			buyCancelErr:  errCancel,
+			status:        ExecutionCompleted,
+			residual:      "0",
+			realizedPnL:   "0.0005",
+			unrealizedPnL: "0",
+			settled:       false,
+			errs:          []string{errCancel.Error(), ErrOrderStuck.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "executor")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			j, err := journal.Open(filepath.Join(dir, "orders.journal"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.Close()
+
+			tickers := NewTickerStore()
+			tickers.Update(&types.Ticker{Exchange: "buy", Pair: pair, Bid: mustDecimal("0.0099"), Ask: mustDecimal("0.01"), Time: time.Now()})
+			tickers.Update(&types.Ticker{Exchange: "sell", Pair: pair, Bid: mustDecimal("0.011"), Ask: mustDecimal("0.0111"), Time: time.Now()})
+
+			rules := NewRulesStore()
+			rules.Update("buy", tt.rules)
+
+			risk := NewRiskManager(&RiskConfig{MaxOrdersPerMinute: tt.maxOrders})
+			o := &Opportunity{
+				Pair:         pair,
+				BuyExchange:  "buy",
+				SellExchange: "sell",
+				BuyPrice:     mustDecimal("0.01"),
+				SellPrice:    mustDecimal("0.011"),
+			}
+			if _, err := risk.Check(o, one, 2); err != nil {
+				t.Fatal(err)
+			}
+
+			traders := map[string]types.Trader{
+				"buy":  newFakeTrader("buy", tt.buyFills, tt.buyCancelErr),
+				"sell": newFakeTrader("sell", tt.sellFills, nil),
+			}
+			executor := NewExecutor(&ExecutorConfig{
+				FillTimeout:    10 * time.Millisecond,
+				PollInterval:   time.Millisecond,
+				CancelTimeout:  20 * time.Millisecond,
+				Policy:         tt.policy,
+				MaxSlippageBps: mustDecimal("50"),
+			}, traders, tickers, rules, risk, j)
+
+			execution := executor.Execute(o, one)
+
+			if execution.Status != tt.status {
+				t.Errorf("status = %s, want %s", execution.Status, tt.status)
+			}
+			assertDecimal(t, "residual", execution.Residual, tt.residual)
+			assertDecimal(t, "realized P&L", execution.RealizedPnL, tt.realizedPnL)
+			assertDecimal(t, "unrealized P&L", execution.UnrealizedPnL, tt.unrealizedPnL)
+
+			if len(execution.HedgeOrders) != len(tt.hedges) {
+				t.Fatalf("got %d hedges, want %d", len(execution.HedgeOrders), len(tt.hedges))
+			}
+			for i, want := range tt.hedges {
+				got := execution.HedgeOrders[i]
+				if got.Exchange != want.exchange || got.Side != want.side {
+					t.Errorf("hedge %d = %s on %s, want %s on %s", i, got.Side, got.Exchange, want.side, want.exchange)
+				}
+				assertDecimal(t, "hedge quantity", got.Quantity, want.quantity)
+			}
+
+			// Unsettled executions stay in the journal for reconcile
+			_, incomplete := j.Incomplete()[execution.ID]
+			if incomplete == tt.settled {
+				t.Errorf("incomplete in journal = %v, want %v", incomplete, !tt.settled)
+			}
+
+			assertErrors(t, execution.Errors, tt.errs)
+		})
+	}
+}
+
+func TestReconcileUnwindsResidual(t *testing.T) {
+	pair := types.Pair{Base: "LTC", Quote: "BTC"}
+
+	dir, err := ioutil.TempDir("", "executor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "orders.journal")
+	j, err := journal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A buy leg was filled before the restart, the sell leg never reached the exchange
+	trader := newFakeTrader("buy", nil, nil)
+	buy, err := j.Intent("execution", "buy", pair, types.Buy, mustDecimal("0.01"), one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, _ := trader.PlaceOrder(pair, types.Buy, mustDecimal("0.01"), one)
+	if err := j.Acknowledge(buy.ClientID, order); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Intent("execution", "buy", pair, types.Sell, mustDecimal("0.011"), one); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	j, err = journal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	tickers := NewTickerStore()
+	tickers.Update(&types.Ticker{Exchange: "buy", Pair: pair, Bid: mustDecimal("0.0099"), Ask: mustDecimal("0.01"), Time: time.Now()})
+
+	executor := NewExecutor(&ExecutorConfig{
+		FillTimeout:    10 * time.Millisecond,
+		PollInterval:   time.Millisecond,
+		CancelTimeout:  20 * time.Millisecond,
+		Policy:         HoldResidual,
+		MaxSlippageBps: decimal.Zero,
+	}, map[string]types.Trader{"buy": trader}, tickers, NewRulesStore(), nil, j)
+
+	executions := executor.Reconcile()
+	if len(executions) != 1 {
+		t.Fatalf("got %d executions, want 1", len(executions))
+	}
+
+	execution := executions[0]
+	assertDecimal(t, "residual", execution.Residual, "0")
+	if len(execution.HedgeOrders) != 1 || execution.HedgeOrders[0].Side != types.Sell {
+		t.Fatalf("hedges = %v, want a single sell", execution.HedgeOrders)
+	}
+	assertDecimal(t, "realized P&L", execution.RealizedPnL, "-0.0001")
+	if len(j.Incomplete()) != 0 {
+		t.Errorf("execution is still incomplete")
+	}
+}
+
+// assertErrors checks each error contains the expected message in order, no errors are expected when empty
+func assertErrors(t *testing.T, errs []error, want []string) {
+	t.Helper()
+
+	if len(want) == 0 && len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+		return
+	}
+
+	i := 0
+	for _, err := range errs {
+		if i < len(want) && strings.Contains(err.Error(), want[i]) {
+			i++
+		}
+	}
+	if i < len(want) {
+		t.Errorf("errors %v do not contain %q", errs, want[i])
+	}
+}
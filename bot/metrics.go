@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"github.com/RichardKnop/arbitrage/metrics"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	tickersReceived = metrics.NewCounter(
+		"arbitrage_tickers_received_total",
+		"Tickers received from exchanges.",
+		"exchange",
+	)
+	quoteAge = metrics.NewHistogram(
+		"arbitrage_quote_age_seconds",
+		"Age of tickers when received, measured from the exchange timestamp.",
+		[]float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+		"exchange",
+	)
+	opportunitiesDetected = metrics.NewCounter(
+		"arbitrage_opportunities_detected_total",
+		"Opportunities detected by type, spread, triangular or cycle.",
+		"type",
+	)
+	opportunitySpread = metrics.NewHistogram(
+		"arbitrage_opportunity_net_spread_bps",
+		"Net spread or return of detected opportunities in basis points.",
+		[]float64{5, 10, 20, 50, 100, 200, 500, 1000},
+		"type",
+	)
+	ordersClosed = metrics.NewCounter(
+		"arbitrage_orders_total",
+		"Orders placed by exchange and their final status.",
+		"exchange",
+		"status",
+	)
+)
+
+func observeOpportunity(kind string, netBps decimal.Decimal) {
+	bps, _ := netBps.Float64()
+	opportunitiesDetected.Inc(kind)
+	opportunitySpread.Observe(bps, kind)
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/storage"
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// saveOpportunity stores a detected spread opportunity
+func (b *Bot) saveOpportunity(o *Opportunity) {
+	if b.cnf.Storage == nil {
+		return
+	}
+
+	err := b.cnf.Storage.SaveOpportunity(&storage.Opportunity{
+		Pair:           o.Pair,
+		BuyExchange:    o.BuyExchange,
+		SellExchange:   o.SellExchange,
+		BuyPrice:       o.BuyPrice,
+		SellPrice:      o.SellPrice,
+		GrossSpreadBps: o.GrossSpreadBps,
+		NetSpreadBps:   o.NetSpreadBps,
+		Quantity:       o.Quantity,
+		DetectedAt:     o.DetectedAt,
+	})
+	if err != nil {
+		log.Printf("Save opportunity error: %v", err)
+	}
+}
+
+// saveExecution stores final states of all orders of the execution, exchanges report
+// average prices only so each order is stored with a single aggregated fill
+func (b *Bot) saveExecution(execution *Execution) {
+	if b.cnf.Storage == nil {
+		return
+	}
+
+	orders := append([]*types.Order{execution.BuyOrder, execution.SellOrder}, execution.HedgeOrders...)
+	for _, order := range orders {
+		if order == nil {
+			continue
+		}
+
+		err := b.cnf.Storage.SaveOrder(&storage.Order{
+			ExecutionID:    execution.ID,
+			ID:             order.ID,
+			Exchange:       order.Exchange,
+			Pair:           order.Pair,
+			Side:           order.Side,
+			Price:          order.Price,
+			Quantity:       order.Quantity,
+			FilledQuantity: order.FilledQuantity,
+			AveragePrice:   order.AveragePrice,
+			Fee:            order.Fee,
+			Status:         order.Status,
+			CreatedAt:      order.CreatedAt,
+			UpdatedAt:      order.UpdatedAt,
+		})
+		if err != nil {
+			log.Printf("Save order %s error: %v", order.ID, err)
+			continue
+		}
+
+		if order.FilledQuantity.Sign() <= 0 {
+			continue
+		}
+
+		fillTime := order.UpdatedAt
+		if fillTime.IsZero() {
+			fillTime = execution.FinishedAt
+		}
+		err = b.cnf.Storage.SaveFill(&storage.Fill{
+			OrderID:  order.ID,
+			Exchange: order.Exchange,
+			Pair:     order.Pair,
+			Side:     order.Side,
+			Price:    order.AveragePrice,
+			Quantity: order.FilledQuantity,
+			Fee:      order.Fee,
+			Time:     fillTime,
+		})
+		if err != nil {
+			log.Printf("Save fill of order %s error: %v", order.ID, err)
+		}
+	}
+}
+
+// saveBalances stores a snapshot of balances fetched from an exchange
+func (b *Bot) saveBalances(exchange string, balances []*types.Balance, at time.Time) {
+	if b.cnf.Storage == nil {
+		return
+	}
+
+	snapshots := make([]*storage.BalanceSnapshot, len(balances))
+	for i, balance := range balances {
+		snapshots[i] = &storage.BalanceSnapshot{
+			Exchange:  exchange,
+			Currency:  balance.Currency,
+			Available: balance.Available,
+			Pending:   balance.Pending,
+			Total:     balance.Total,
+			Time:      at,
+		}
+	}
+
+	if err := b.cnf.Storage.SaveBalances(snapshots); err != nil {
+		log.Printf("[%s] Save balances error: %v", exchange, err)
+	}
+}
+
+// saveTransfer stores a proposed or initiated rebalancing transfer
+func (b *Bot) saveTransfer(t *Transfer) {
+	if b.cnf.Storage == nil {
+		return
+	}
+
+	err := b.cnf.Storage.SaveTransfer(&storage.Transfer{
+		Currency:     t.Currency,
+		Asset:        t.Asset,
+		From:         t.From,
+		To:           t.To,
+		Amount:       t.Amount,
+		AssetAmount:  t.AssetAmount,
+		Fee:          t.Fee,
+		Status:       string(t.Status),
+		WithdrawalID: t.WithdrawalID,
+		Error:        t.Error,
+		CreatedAt:    t.CreatedAt,
+	})
+	if err != nil {
+		log.Printf("Save transfer error: %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// TransferStatus ...
+type TransferStatus string
+
+const (
+	// TransferProposed means the transfer waits for review, no funds have moved
+	TransferProposed TransferStatus = "proposed"
+	// TransferInitiated means the withdrawal has been requested in automatic mode
+	TransferInitiated TransferStatus = "initiated"
+	// TransferFailed means the withdrawal request failed in automatic mode
+	TransferFailed TransferStatus = "failed"
+)
+
+// RebalanceConfig stores inventory rebalancing options
+type RebalanceConfig struct {
+	// Targets are desired shares of total holdings keyed by currency and exchange, e.g. BTC: {bittrex: 0.5, poloniex: 0.5}
+	Targets   map[string]map[string]decimal.Decimal
+	Tolerance decimal.Decimal // how far from its target a share can drift before a transfer is proposed
+	// Via lists currencies we are willing to convert to and transfer instead, e.g. LTC is cheaper and faster than BTC
+	Via []string
+	// ConfirmationCostBps is how much of the transferred value waiting for one more confirmation is worth
+	ConfirmationCostBps decimal.Decimal
+	Interval            time.Duration // how often balances are checked
+	Automatic           bool          // initiate direct transfers, otherwise only propose them
+	// TransferTimeout is how long an initiated transfer counts as in transit unless the
+	// destination credits it earlier, zero means until it is credited
+	TransferTimeout time.Duration
+}
+
+// Transfer moves funds of a skewed currency between exchanges, possibly converted to another asset
+type Transfer struct {
+	Currency      string          // skewed currency
+	Asset         string          // currency actually withdrawn, converted from and to Currency when different
+	From          string          // exchange with surplus
+	To            string          // exchange with deficit
+	Amount        decimal.Decimal // in units of Currency
+	AssetAmount   decimal.Decimal // in units of Asset
+	Fee           decimal.Decimal // withdrawal fee in units of Asset
+	FeeBps        decimal.Decimal // withdrawal fee as a share of the transferred value
+	Confirmations int             // needed before the deposit is credited
+	Status        TransferStatus
+	WithdrawalID  string
+	Error         string
+	CreatedAt     time.Time
+}
+
+// RequiresConversion returns true when the asset has to be traded for before and after the transfer
+func (t *Transfer) RequiresConversion() bool {
+	return t.Asset != t.Currency
+}
+
+// proposalKey identifies what the transfer moves where, regardless of when it was planned
+func (t *Transfer) proposalKey() string {
+	return fmt.Sprintf("%s %s %s->%s %s", t.Currency, t.Asset, t.From, t.To, t.Amount)
+}
+
+// Rebalancer watches per exchange balances and proposes transfers when they drift from targets
+type Rebalancer struct {
+	cnf         *RebalanceConfig
+	fees        *fees.Model
+	balances    *BalanceStore
+	currencies  *CurrencyStore
+	tickers     *TickerStore
+	withdrawers map[string]types.Withdrawer
+	proposals   []*Transfer
+	inTransit   []*pendingTransfer
+	mu          sync.RWMutex
+}
+
+// pendingTransfer is an initiated transfer the destination has not credited yet, the
+// withdrawn funds are missing from balances of both exchanges until then
+type pendingTransfer struct {
+	transfer *Transfer
+	credited decimal.Decimal // destination balance of the asset once the deposit arrives
+}
+
+// NewRebalancer returns new Rebalancer instance
+func NewRebalancer(cnf *RebalanceConfig, feeModel *fees.Model, balances *BalanceStore, currencies *CurrencyStore, tickers *TickerStore, withdrawers map[string]types.Withdrawer) *Rebalancer {
+	return &Rebalancer{
+		cnf:         cnf,
+		fees:        feeModel,
+		balances:    balances,
+		currencies:  currencies,
+		tickers:     tickers,
+		withdrawers: withdrawers,
+	}
+}
+
+// Proposals returns transfers planned by the latest Rebalance call
+func (r *Rebalancer) Proposals() []*Transfer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	proposals := make([]*Transfer, len(r.proposals))
+	copy(proposals, r.proposals)
+	return proposals
+}
+
+// Rebalance plans transfers for all skewed currencies and in automatic mode initiates direct
+// ones, currencies with a transfer in transit are not transferred again until it arrives
+func (r *Rebalancer) Rebalance() []*Transfer {
+	r.settle(time.Now())
+
+	currencies := make([]string, 0, len(r.cnf.Targets))
+	for currency := range r.cnf.Targets {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var transfers []*Transfer
+	for _, currency := range currencies {
+		transfers = append(transfers, r.plan(currency)...)
+	}
+
+	if r.cnf.Automatic {
+		busy := make(map[string]bool)
+		for _, p := range r.pending() {
+			busy[p.transfer.Currency] = true
+		}
+
+		for _, t := range transfers {
+			if t.RequiresConversion() || busy[t.Currency] {
+				continue
+			}
+
+			r.initiate(t)
+			if t.Status == TransferInitiated {
+				r.track(t)
+				busy[t.Currency] = true
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.proposals = transfers
+	r.mu.Unlock()
+
+	return transfers
+}
+
+// InTransit returns initiated transfers the destination has not credited yet
+func (r *Rebalancer) InTransit() []*Transfer {
+	pending := r.pending()
+	transfers := make([]*Transfer, len(pending))
+	for i, p := range pending {
+		transfers[i] = p.transfer
+	}
+	return transfers
+}
+
+func (r *Rebalancer) pending() []*pendingTransfer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pending := make([]*pendingTransfer, len(r.inTransit))
+	copy(pending, r.inTransit)
+	return pending
+}
+
+// track keeps the transfer in transit until the destination balance grows by what it receives
+func (r *Rebalancer) track(t *Transfer) {
+	credited := t.AssetAmount.Sub(t.Fee)
+	if balance, ok := r.balances.Get(t.To, t.Asset); ok {
+		credited = credited.Add(balance.Total)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inTransit = append(r.inTransit, &pendingTransfer{transfer: t, credited: credited})
+}
+
+// settle forgets transfers which were credited or timed out
+func (r *Rebalancer) settle(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := r.inTransit[:0]
+	for _, p := range r.inTransit {
+		t := p.transfer
+		if balance, ok := r.balances.Get(t.To, t.Asset); ok && balance.Total.GreaterThanOrEqual(p.credited) {
+			continue
+		}
+		if r.cnf.TransferTimeout > 0 && now.Sub(t.CreatedAt) >= r.cnf.TransferTimeout {
+			continue
+		}
+		pending = append(pending, p)
+	}
+	r.inTransit = pending
+}
+
+type skew struct {
+	exchange  string
+	amount    decimal.Decimal // surplus when positive, deficit when negative
+	available decimal.Decimal // surplus not reserved by open orders which can be withdrawn
+}
+
+// plan pairs the largest surplus with the largest deficit until no exchange is outside tolerance
+func (r *Rebalancer) plan(currency string) []*Transfer {
+	targets := r.cnf.Targets[currency]
+
+	total := decimal.Zero
+	held := make(map[string]decimal.Decimal, len(targets))
+	for exchange := range targets {
+		// Cannot judge skew without knowing every balance
+		if !r.balances.Known(exchange) {
+			return nil
+		}
+		if balance, ok := r.balances.Get(exchange, currency); ok {
+			held[exchange] = balance.Total
+			total = total.Add(balance.Total)
+		}
+	}
+
+	// Funds in transit already left the source, count them as held by the destination
+	for _, p := range r.pending() {
+		t := p.transfer
+		if t.Currency != currency {
+			continue
+		}
+		if _, ok := targets[t.To]; ok {
+			amount := t.AssetAmount.Sub(t.Fee)
+			held[t.To] = held[t.To].Add(amount)
+			total = total.Add(amount)
+		}
+	}
+
+	if total.Sign() <= 0 {
+		return nil
+	}
+
+	var surpluses, deficits []*skew
+	for exchange, target := range targets {
+		drift := held[exchange].Div(total).Sub(target)
+		if drift.Abs().LessThanOrEqual(r.cnf.Tolerance) {
+			continue
+		}
+
+		s := &skew{
+			exchange:  exchange,
+			amount:    drift.Mul(total),
+			available: r.balances.Available(exchange, currency),
+		}
+		if s.amount.Sign() > 0 {
+			surpluses = append(surpluses, s)
+		} else {
+			deficits = append(deficits, s)
+		}
+	}
+
+	sort.Slice(surpluses, func(i, j int) bool { return surpluses[i].amount.GreaterThan(surpluses[j].amount) })
+	sort.Slice(deficits, func(i, j int) bool { return deficits[i].amount.LessThan(deficits[j].amount) })
+
+	var transfers []*Transfer
+	for len(surpluses) > 0 && len(deficits) > 0 {
+		from, to := surpluses[0], deficits[0]
+
+		// Reserved funds cannot be withdrawn
+		amount := decimal.Min(from.amount, to.amount.Neg(), from.available)
+		if amount.Sign() > 0 {
+			if t := r.chooseAsset(currency, from.exchange, to.exchange, amount); t != nil {
+				transfers = append(transfers, t)
+			}
+		}
+
+		from.amount = from.amount.Sub(amount)
+		from.available = from.available.Sub(amount)
+		to.amount = to.amount.Add(amount)
+		if from.amount.Sign() <= 0 || from.available.Sign() <= 0 {
+			surpluses = surpluses[1:]
+		}
+		if to.amount.Sign() >= 0 {
+			deficits = deficits[1:]
+		}
+	}
+
+	return transfers
+}
+
+// chooseAsset ranks the currency itself and configured alternatives by withdrawal fee
+// and confirmation time, both expressed in bps of the transferred value
+func (r *Rebalancer) chooseAsset(currency, from, to string, amount decimal.Decimal) *Transfer {
+	var (
+		best      *Transfer
+		bestScore decimal.Decimal
+	)
+	for _, asset := range append([]string{currency}, r.cnf.Via...) {
+		t := r.candidate(currency, asset, from, to, amount)
+		if t == nil {
+			continue
+		}
+
+		score := t.FeeBps.Add(r.cnf.ConfirmationCostBps.Mul(decimal.New(int64(t.Confirmations), 0)))
+		if best == nil || score.LessThan(bestScore) {
+			best, bestScore = t, score
+		}
+	}
+
+	return best
+}
+
+func (r *Rebalancer) candidate(currency, asset, from, to string, amount decimal.Decimal) *Transfer {
+	fee, ok := r.fees.Withdrawal(from, asset)
+	if !ok {
+		return nil
+	}
+
+	source, ok := r.currencies.Get(from, asset)
+	if !ok || !source.Active {
+		return nil
+	}
+
+	destination, ok := r.currencies.Get(to, asset)
+	if !ok || !destination.Active {
+		return nil
+	}
+
+	// Alternative assets are bought with the currency on the source exchange
+	assetAmount, feeValue := amount, fee
+	if asset != currency {
+		ticker, ok := r.tickers.Get(from, types.Pair{Base: asset, Quote: currency})
+		if !ok || ticker.Ask.Sign() <= 0 || ticker.Bid.Sign() <= 0 {
+			return nil
+		}
+		assetAmount = amount.Div(ticker.Ask)
+		feeValue = fee.Mul(ticker.Bid)
+	}
+
+	return &Transfer{
+		Currency:      currency,
+		Asset:         asset,
+		From:          from,
+		To:            to,
+		Amount:        amount,
+		AssetAmount:   assetAmount,
+		Fee:           fee,
+		FeeBps:        feeValue.Div(amount).Mul(bpsPerUnit),
+		Confirmations: destination.MinConfirmations,
+		Status:        TransferProposed,
+		CreatedAt:     time.Now(),
+	}
+}
+
+func (r *Rebalancer) initiate(t *Transfer) {
+	source, ok := r.withdrawers[t.From]
+	if !ok {
+		t.fail(fmt.Errorf("%s does not support withdrawals", t.From))
+		return
+	}
+
+	destination, ok := r.withdrawers[t.To]
+	if !ok {
+		t.fail(fmt.Errorf("%s does not support deposits", t.To))
+		return
+	}
+
+	address, err := destination.FetchDepositAddress(t.Asset)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+
+	id, err := source.RequestWithdrawal(t.Asset, t.AssetAmount, address)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+
+	t.Status = TransferInitiated
+	t.WithdrawalID = id
+}
+
+func (t *Transfer) fail(err error) {
+	t.Status = TransferFailed
+	t.Error = err.Error()
+}
@@ -0,0 +1,275 @@
+package bot
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+type fakeWithdrawer struct {
+	name        string
+	err         error
+	withdrawals []string
+	mu          sync.Mutex
+}
+
+func (w *fakeWithdrawer) RequestWithdrawal(currency string, quantity decimal.Decimal, address *types.DepositAddress) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return "", w.err
+	}
+	w.withdrawals = append(w.withdrawals, quantity.String()+" "+currency+" to "+address.Address)
+	return "withdrawal", nil
+}
+
+func (w *fakeWithdrawer) FetchDepositAddress(currency string) (*types.DepositAddress, error) {
+	return &types.DepositAddress{Address: w.name}, nil
+}
+
+// newRebalancer returns a rebalancer of BTC between exchanges holding the amounts,
+// all of it available unless listed in available
+func newRebalancer(cnf *RebalanceConfig, held, available map[string]string) (*Rebalancer, map[string]*fakeWithdrawer) {
+	feeModel := fees.New(new(fees.Config))
+	balances := NewBalanceStore()
+	currencies := NewCurrencyStore()
+	withdrawers := make(map[string]types.Withdrawer)
+	fakes := make(map[string]*fakeWithdrawer)
+	for exchange, amount := range held {
+		free := amount
+		if a, ok := available[exchange]; ok {
+			free = a
+		}
+		balances.Update(exchange, []*types.Balance{{Currency: "BTC", Total: mustDecimal(amount), Available: mustDecimal(free), Pending: decimal.Zero}})
+		currencies.Update(exchange, []*types.Currency{
+			{Currency: "BTC", WithdrawalFee: mustDecimal("0.001"), MinConfirmations: 2, Active: true},
+			{Currency: "LTC", WithdrawalFee: mustDecimal("0.01"), MinConfirmations: 6, Active: true},
+		})
+		feeModel.SetWithdrawalFees(exchange, map[string]decimal.Decimal{"BTC": mustDecimal("0.001"), "LTC": mustDecimal("0.01")})
+
+		fakes[exchange] = &fakeWithdrawer{name: exchange}
+		withdrawers[exchange] = fakes[exchange]
+	}
+
+	return NewRebalancer(cnf, feeModel, balances, currencies, NewTickerStore(), withdrawers), fakes
+}
+
+func TestRebalancePlan(t *testing.T) {
+	even := map[string]map[string]decimal.Decimal{
+		"BTC": {"a": mustDecimal("0.5"), "b": mustDecimal("0.5")},
+	}
+
+	tests := []struct {
+		name      string
+		targets   map[string]map[string]decimal.Decimal
+		held      map[string]string
+		available map[string]string
+		inTransit []*Transfer
+		transfers []string // from->to amount, sorted
+	}{
+		{
+			name:    "within tolerance",
+			targets: even,
+			held:    map[string]string{"a": "5.5", "b": "4.5"},
+		},
+		{
+			name:      "surplus moved to deficit",
+			targets:   even,
+			held:      map[string]string{"a": "8", "b": "2"},
+			transfers: []string{"a->b 3"},
+		},
+		{
+			name:      "reserved funds stay",
+			targets:   even,
+			held:      map[string]string{"a": "8", "b": "2"},
+			available: map[string]string{"a": "1"},
+			transfers: []string{"a->b 1"},
+		},
+		{
+			name:    "balance of a target exchange unknown",
+			targets: map[string]map[string]decimal.Decimal{"BTC": {"a": mustDecimal("0.5"), "c": mustDecimal("0.5")}},
+			held:    map[string]string{"a": "8", "b": "2"},
+		},
+		{
+			name:      "transfer in transit counted at destination",
+			targets:   even,
+			held:      map[string]string{"a": "5", "b": "2"},
+			inTransit: []*Transfer{{Currency: "BTC", Asset: "BTC", To: "b", AssetAmount: mustDecimal("3.001"), Fee: mustDecimal("0.001"), CreatedAt: time.Now()}},
+		},
+		{
+			name: "surplus split between deficits",
+			targets: map[string]map[string]decimal.Decimal{
+				"BTC": {"a": mustDecimal("0.4"), "b": mustDecimal("0.3"), "c": mustDecimal("0.3")},
+			},
+			held:      map[string]string{"a": "10", "b": "0", "c": "0"},
+			transfers: []string{"a->b 3", "a->c 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newRebalancer(&RebalanceConfig{
+				Targets:   tt.targets,
+				Tolerance: mustDecimal("0.1"),
+			}, tt.held, tt.available)
+			for _, transfer := range tt.inTransit {
+				r.track(transfer)
+			}
+
+			var transfers []string
+			for _, transfer := range r.plan("BTC") {
+				transfers = append(transfers, transfer.From+"->"+transfer.To+" "+transfer.Amount.String())
+			}
+			sort.Strings(transfers)
+
+			if len(transfers) != len(tt.transfers) {
+				t.Fatalf("transfers = %v, want %v", transfers, tt.transfers)
+			}
+			for i := range transfers {
+				if transfers[i] != tt.transfers[i] {
+					t.Errorf("transfer %d = %s, want %s", i, transfers[i], tt.transfers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChooseAsset(t *testing.T) {
+	tests := []struct {
+		name             string
+		confirmationCost string
+		ltcActive        bool
+		ltcTicker        bool
+		asset            string
+		assetAmount      string
+		feeBps           string
+	}{
+		{
+			name:             "cheaper alternative",
+			confirmationCost: "1",
+			ltcActive:        true,
+			ltcTicker:        true,
+			asset:            "LTC",
+			assetAmount:      "100",
+			feeBps:           "0.99",
+		},
+		{
+			name:             "confirmations outweigh the fee",
+			confirmationCost: "5",
+			ltcActive:        true,
+			ltcTicker:        true,
+			asset:            "BTC",
+			assetAmount:      "1",
+			feeBps:           "10",
+		},
+		{
+			name:             "alternative disabled",
+			confirmationCost: "1",
+			ltcTicker:        true,
+			asset:            "BTC",
+			assetAmount:      "1",
+			feeBps:           "10",
+		},
+		{
+			name:             "alternative without quote",
+			confirmationCost: "1",
+			ltcActive:        true,
+			asset:            "BTC",
+			assetAmount:      "1",
+			feeBps:           "10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newRebalancer(&RebalanceConfig{
+				Via:                 []string{"LTC"},
+				ConfirmationCostBps: mustDecimal(tt.confirmationCost),
+			}, map[string]string{"a": "1", "b": "1"}, nil)
+			if !tt.ltcActive {
+				r.currencies.Update("b", []*types.Currency{
+					{Currency: "BTC", WithdrawalFee: mustDecimal("0.001"), MinConfirmations: 2, Active: true},
+					{Currency: "LTC", WithdrawalFee: mustDecimal("0.01"), MinConfirmations: 6},
+				})
+			}
+			if tt.ltcTicker {
+				r.tickers.Update(&types.Ticker{Exchange: "a", Pair: types.Pair{Base: "LTC", Quote: "BTC"}, Bid: mustDecimal("0.0099"), Ask: mustDecimal("0.01"), Time: time.Now()})
+			}
+
+			transfer := r.chooseAsset("BTC", "a", "b", one)
+			if transfer == nil {
+				t.Fatal("no transfer")
+			}
+			if transfer.Asset != tt.asset {
+				t.Errorf("asset = %s, want %s", transfer.Asset, tt.asset)
+			}
+			if transfer.RequiresConversion() != (tt.asset != "BTC") {
+				t.Errorf("requires conversion = %v", transfer.RequiresConversion())
+			}
+			assertDecimal(t, "asset amount", transfer.AssetAmount, tt.assetAmount)
+			assertDecimal(t, "fee bps", transfer.FeeBps, tt.feeBps)
+		})
+	}
+}
+
+func TestRebalanceAutomatic(t *testing.T) {
+	r, withdrawers := newRebalancer(&RebalanceConfig{
+		Targets: map[string]map[string]decimal.Decimal{
+			"BTC": {"a": mustDecimal("0.4"), "b": mustDecimal("0.3"), "c": mustDecimal("0.3")},
+		},
+		Tolerance: mustDecimal("0.1"),
+		Automatic: true,
+	}, map[string]string{"a": "10", "b": "0", "c": "0"}, nil)
+
+	// A single withdrawal of the currency is initiated at a time
+	transfers := r.Rebalance()
+	if len(transfers) != 2 {
+		t.Fatalf("got %d transfers, want 2", len(transfers))
+	}
+	statuses := []TransferStatus{transfers[0].Status, transfers[1].Status}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+	if statuses[0] != TransferInitiated || statuses[1] != TransferProposed {
+		t.Errorf("statuses = %v, want one initiated and one proposed", statuses)
+	}
+	if len(withdrawers["a"].withdrawals) != 1 {
+		t.Fatalf("withdrawals = %v, want 1", withdrawers["a"].withdrawals)
+	}
+	if len(r.InTransit()) != 1 {
+		t.Fatalf("got %d transfers in transit, want 1", len(r.InTransit()))
+	}
+
+	// Until the deposit is credited the remaining deficit is only proposed
+	r.balances.Update("a", []*types.Balance{{Currency: "BTC", Total: mustDecimal("7"), Available: mustDecimal("7"), Pending: decimal.Zero}})
+	transfers = r.Rebalance()
+	if len(transfers) != 1 || transfers[0].Status != TransferProposed {
+		t.Errorf("transfers after initiating = %v, want one proposed", transfers)
+	}
+	if len(withdrawers["a"].withdrawals) != 1 {
+		t.Errorf("withdrawals = %v, want 1", withdrawers["a"].withdrawals)
+	}
+
+	// Failed withdrawals are not in transit so the next transfer is attempted
+	withdrawers["a"].err = errors.New("withdrawal error")
+	r.mu.Lock()
+	r.inTransit = nil
+	r.mu.Unlock()
+	transfers = r.Rebalance()
+	if len(transfers) != 2 {
+		t.Fatalf("got %d transfers, want 2", len(transfers))
+	}
+	for _, transfer := range transfers {
+		if transfer.Status != TransferFailed || transfer.Error != "withdrawal error" {
+			t.Errorf("transfer to %s %s: %s, want failed", transfer.To, transfer.Status, transfer.Error)
+		}
+	}
+	if len(r.InTransit()) != 0 {
+		t.Errorf("got %d transfers in transit, want 0", len(r.InTransit()))
+	}
+}
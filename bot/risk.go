@@ -0,0 +1,299 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// RejectReason is a machine readable reason for rejecting a trade
+type RejectReason string
+
+const (
+	// RejectKillSwitch means trading has been halted manually
+	RejectKillSwitch RejectReason = "kill_switch"
+	// RejectDailyLossLimit means marked loss for the day breached the limit, trading stays halted until reset
+	RejectDailyLossLimit RejectReason = "daily_loss_limit"
+	// RejectTradeNotional means the trade itself is too big
+	RejectTradeNotional RejectReason = "max_trade_notional"
+	// RejectPairNotional means open notional in the pair would be too big
+	RejectPairNotional RejectReason = "max_pair_notional"
+	// RejectExchangeExposure means open notional on one of the exchanges would be too big
+	RejectExchangeExposure RejectReason = "max_exchange_exposure"
+	// RejectOrderRate means too many orders have been placed recently
+	RejectOrderRate RejectReason = "max_order_rate"
+)
+
+// RiskError is returned for every trade rejected by the risk manager
+type RiskError struct {
+	Reason  RejectReason
+	Message string
+}
+
+// Error implements the error interface
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("Trade rejected (%s): %s", e.Reason, e.Message)
+}
+
+// RiskConfig stores pre-trade risk limits, notional limits are in quote currency
+// and a missing entry means there is no limit
+type RiskConfig struct {
+	MaxTradeNotional    map[string]decimal.Decimal // per quote currency
+	MaxPairNotional     map[string]decimal.Decimal // open notional per pair, keyed by pair, e.g. LTC/BTC
+	MaxExchangeExposure map[string]decimal.Decimal // open notional per exchange, keyed by quote currency
+	DailyLossLimit      map[string]decimal.Decimal // loss per UTC day with residuals marked at latest quotes, keyed by quote currency
+	MaxOrdersPerMinute  int                        // zero means no limit
+}
+
+// Reservation holds exposure of a trade approved by the risk manager until it completes
+type Reservation struct {
+	opportunity *Opportunity
+	notional    decimal.Decimal
+}
+
+// position is residual inventory left by an execution, its exposure stays reserved while we hold it
+type position struct {
+	pair     types.Pair
+	exchange string
+	notional decimal.Decimal
+}
+
+// RiskManager approves trades before any order is placed, safe for concurrent use
+type RiskManager struct {
+	cnf              *RiskConfig
+	pairExposure     map[types.Pair]decimal.Decimal
+	exchangeExposure map[string]map[string]decimal.Decimal // exchange -> quote currency -> notional
+	positions        map[string]*position                  // keyed by execution ID
+	dailyPnL         map[string]decimal.Decimal
+	day              string
+	orderTimes       []time.Time
+	killSwitch       bool
+	lossLimitHalt    bool
+	mu               sync.Mutex
+}
+
+// NewRiskManager returns new RiskManager instance
+func NewRiskManager(cnf *RiskConfig) *RiskManager {
+	return &RiskManager{
+		cnf:              cnf,
+		pairExposure:     make(map[types.Pair]decimal.Decimal),
+		exchangeExposure: make(map[string]map[string]decimal.Decimal),
+		positions:        make(map[string]*position),
+		dailyPnL:         make(map[string]decimal.Decimal),
+	}
+}
+
+// Check approves trading the opportunity for the quantity and reserves its exposure,
+// the reservation must be released by calling Complete once the execution finishes
+func (r *RiskManager) Check(o *Opportunity, quantity decimal.Decimal, orders int) (*Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.rollDay(now)
+
+	if r.killSwitch {
+		return nil, &RiskError{Reason: RejectKillSwitch, Message: "Kill switch engaged"}
+	}
+
+	if r.lossLimitHalt {
+		return nil, &RiskError{Reason: RejectDailyLossLimit, Message: "Halted until manual reset"}
+	}
+
+	quote := o.Pair.Quote
+	notional := quantity.Mul(o.BuyPrice)
+
+	if limit, ok := r.cnf.MaxTradeNotional[quote]; ok && notional.GreaterThan(limit) {
+		return nil, &RiskError{
+			Reason:  RejectTradeNotional,
+			Message: fmt.Sprintf("%s %s above %s %s", notional, quote, limit, quote),
+		}
+	}
+
+	if limit, ok := r.cnf.MaxPairNotional[o.Pair.String()]; ok && r.pairExposure[o.Pair].Add(notional).GreaterThan(limit) {
+		return nil, &RiskError{
+			Reason:  RejectPairNotional,
+			Message: fmt.Sprintf("%s open notional would exceed %s %s", o.Pair, limit, quote),
+		}
+	}
+
+	if limit, ok := r.cnf.MaxExchangeExposure[quote]; ok {
+		for _, exchange := range []string{o.BuyExchange, o.SellExchange} {
+			if r.exchangeExposure[exchange][quote].Add(notional).GreaterThan(limit) {
+				return nil, &RiskError{
+					Reason:  RejectExchangeExposure,
+					Message: fmt.Sprintf("%s exposure would exceed %s %s", exchange, limit, quote),
+				}
+			}
+		}
+	}
+
+	if r.cnf.MaxOrdersPerMinute > 0 {
+		r.pruneOrderTimes(now)
+		if len(r.orderTimes)+orders > r.cnf.MaxOrdersPerMinute {
+			return nil, &RiskError{
+				Reason:  RejectOrderRate,
+				Message: fmt.Sprintf("%d orders placed in the last minute", len(r.orderTimes)),
+			}
+		}
+	}
+
+	// All checks passed, reserve the exposure
+	for i := 0; i < orders; i++ {
+		r.orderTimes = append(r.orderTimes, now)
+	}
+	r.pairExposure[o.Pair] = r.pairExposure[o.Pair].Add(notional)
+	r.addExchangeExposure(o.BuyExchange, quote, notional)
+	r.addExchangeExposure(o.SellExchange, quote, notional)
+
+	return &Reservation{opportunity: o, notional: notional}, nil
+}
+
+// Complete releases exposure of the reservation and records P&L of the execution with
+// its residual marked at the latest quote, breaching the daily loss limit halts trading
+// until ResetLossLimit is called. Exposure of the residual stays reserved until a later
+// execution with the same ID, e.g. resumed by reconcile, completes without residual.
+// Reservation is nil for executions resumed by reconcile.
+func (r *RiskManager) Complete(reservation *Reservation, execution *Execution) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reservation != nil {
+		o := reservation.opportunity
+		r.pairExposure[o.Pair] = r.pairExposure[o.Pair].Sub(reservation.notional)
+		r.addExchangeExposure(o.BuyExchange, o.Pair.Quote, reservation.notional.Neg())
+		r.addExchangeExposure(o.SellExchange, o.Pair.Quote, reservation.notional.Neg())
+	}
+
+	if p, ok := r.positions[execution.ID]; ok {
+		r.pairExposure[p.pair] = r.pairExposure[p.pair].Sub(p.notional)
+		r.addExchangeExposure(p.exchange, p.pair.Quote, p.notional.Neg())
+		delete(r.positions, execution.ID)
+	}
+
+	o := execution.Opportunity
+	if residual := execution.Residual; residual.Sign() != 0 {
+		p := &position{pair: o.Pair, exchange: o.BuyExchange, notional: residual.Mul(averagePrice(execution.BuyOrder))}
+		if residual.Sign() < 0 {
+			p.exchange, p.notional = o.SellExchange, residual.Neg().Mul(averagePrice(execution.SellOrder))
+		}
+		r.positions[execution.ID] = p
+		r.pairExposure[p.pair] = r.pairExposure[p.pair].Add(p.notional)
+		r.addExchangeExposure(p.exchange, p.pair.Quote, p.notional)
+	}
+
+	r.rollDay(time.Now())
+
+	quote := o.Pair.Quote
+	r.dailyPnL[quote] = r.dailyPnL[quote].Add(execution.RealizedPnL).Add(execution.UnrealizedPnL)
+	if limit, ok := r.cnf.DailyLossLimit[quote]; ok && r.dailyPnL[quote].Neg().GreaterThan(limit) {
+		log.Printf("Daily loss limit breached: %s %s, trading halted", r.dailyPnL[quote], quote)
+		r.lossLimitHalt = true
+	}
+}
+
+// CheckHedge approves an unwind or rehedge order of a residual position and counts it
+// towards the order rate. Hedges lower exposure so neither notional limits nor the kill
+// switch apply to them.
+func (r *RiskManager) CheckHedge() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.cnf.MaxOrdersPerMinute > 0 {
+		r.pruneOrderTimes(now)
+		if len(r.orderTimes)+1 > r.cnf.MaxOrdersPerMinute {
+			return &RiskError{
+				Reason:  RejectOrderRate,
+				Message: fmt.Sprintf("%d orders placed in the last minute", len(r.orderTimes)),
+			}
+		}
+	}
+
+	r.orderTimes = append(r.orderTimes, now)
+	return nil
+}
+
+// Halt engages the kill switch, no trade is approved until Resume is called
+func (r *RiskManager) Halt() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.killSwitch = true
+}
+
+// Resume disengages the kill switch
+func (r *RiskManager) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.killSwitch = false
+}
+
+// ResetLossLimit allows trading again after the daily loss limit was breached
+func (r *RiskManager) ResetLossLimit() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lossLimitHalt = false
+	r.dailyPnL = make(map[string]decimal.Decimal)
+}
+
+// Halted returns true when no trade can be approved
+func (r *RiskManager) Halted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.killSwitch || r.lossLimitHalt
+}
+
+// DailyPnL returns realized P&L of the current UTC day keyed by quote currency
+func (r *RiskManager) DailyPnL() map[string]decimal.Decimal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rollDay(time.Now())
+
+	pnl := make(map[string]decimal.Decimal, len(r.dailyPnL))
+	for currency, amount := range r.dailyPnL {
+		pnl[currency] = amount
+	}
+
+	return pnl
+}
+
+// rollDay resets daily P&L at UTC midnight, loss limit halt stays until manual reset
+func (r *RiskManager) rollDay(now time.Time) {
+	day := now.UTC().Format("2006-01-02")
+	if day != r.day {
+		r.day = day
+		r.dailyPnL = make(map[string]decimal.Decimal)
+	}
+}
+
+func (r *RiskManager) pruneOrderTimes(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(r.orderTimes) && r.orderTimes[i].Before(cutoff) {
+		i++
+	}
+	r.orderTimes = r.orderTimes[i:]
+}
+
+func (r *RiskManager) addExchangeExposure(exchange, currency string, notional decimal.Decimal) {
+	if _, ok := r.exchangeExposure[exchange]; !ok {
+		r.exchangeExposure[exchange] = make(map[string]decimal.Decimal)
+	}
+	r.exchangeExposure[exchange][currency] = r.exchangeExposure[exchange][currency].Add(notional)
+}
+
+func averagePrice(order *types.Order) decimal.Decimal {
+	if order == nil {
+		return decimal.Zero
+	}
+	return order.AveragePrice
+}
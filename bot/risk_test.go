@@ -0,0 +1,283 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+func TestRiskCheck(t *testing.T) {
+	ltc := types.Pair{Base: "LTC", Quote: "BTC"}
+	eth := types.Pair{Base: "ETH", Quote: "BTC"}
+	opportunity := func(pair types.Pair, buy, sell string) *Opportunity {
+		return &Opportunity{Pair: pair, BuyExchange: buy, SellExchange: sell, BuyPrice: mustDecimal("0.01")}
+	}
+
+	tests := []struct {
+		name     string
+		cnf      *RiskConfig
+		halt     bool
+		previous []*Opportunity // approved before, each for quantity of 10
+		o        *Opportunity
+		quantity string
+		reason   RejectReason // empty when approved
+	}{
+		{
+			name:     "no limits",
+			cnf:      new(RiskConfig),
+			o:        opportunity(ltc, "a", "b"),
+			quantity: "1000",
+		},
+		{
+			name:     "kill switch",
+			cnf:      new(RiskConfig),
+			halt:     true,
+			o:        opportunity(ltc, "a", "b"),
+			quantity: "1",
+			reason:   RejectKillSwitch,
+		},
+		{
+			name:     "trade notional at limit",
+			cnf:      &RiskConfig{MaxTradeNotional: map[string]decimal.Decimal{"BTC": mustDecimal("0.1")}},
+			o:        opportunity(ltc, "a", "b"),
+			quantity: "10",
+		},
+		{
+			name:     "trade notional above limit",
+			cnf:      &RiskConfig{MaxTradeNotional: map[string]decimal.Decimal{"BTC": mustDecimal("0.1")}},
+			o:        opportunity(ltc, "a", "b"),
+			quantity: "11",
+			reason:   RejectTradeNotional,
+		},
+		{
+			name:     "trade notional of other quote currency",
+			cnf:      &RiskConfig{MaxTradeNotional: map[string]decimal.Decimal{"ETH": mustDecimal("0.1")}},
+			o:        opportunity(ltc, "a", "b"),
+			quantity: "11",
+		},
+		{
+			name:     "pair notional",
+			cnf:      &RiskConfig{MaxPairNotional: map[string]decimal.Decimal{"LTC/BTC": mustDecimal("0.15")}},
+			previous: []*Opportunity{opportunity(ltc, "a", "b")},
+			o:        opportunity(ltc, "c", "d"),
+			quantity: "10",
+			reason:   RejectPairNotional,
+		},
+		{
+			name:     "pair notional of other pair",
+			cnf:      &RiskConfig{MaxPairNotional: map[string]decimal.Decimal{"LTC/BTC": mustDecimal("0.15")}},
+			previous: []*Opportunity{opportunity(eth, "a", "b")},
+			o:        opportunity(ltc, "a", "b"),
+			quantity: "10",
+		},
+		{
+			name:     "exchange exposure on sell exchange",
+			cnf:      &RiskConfig{MaxExchangeExposure: map[string]decimal.Decimal{"BTC": mustDecimal("0.15")}},
+			previous: []*Opportunity{opportunity(eth, "a", "b")},
+			o:        opportunity(ltc, "c", "b"),
+			quantity: "10",
+			reason:   RejectExchangeExposure,
+		},
+		{
+			name:     "exchange exposure on other exchanges",
+			cnf:      &RiskConfig{MaxExchangeExposure: map[string]decimal.Decimal{"BTC": mustDecimal("0.15")}},
+			previous: []*Opportunity{opportunity(eth, "a", "b")},
+			o:        opportunity(ltc, "c", "d"),
+			quantity: "10",
+		},
+		{
+			name:     "order rate",
+			cnf:      &RiskConfig{MaxOrdersPerMinute: 3},
+			previous: []*Opportunity{opportunity(eth, "a", "b")},
+			o:        opportunity(ltc, "a", "b"),
+			quantity: "1",
+			reason:   RejectOrderRate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRiskManager(tt.cnf)
+			for _, o := range tt.previous {
+				if _, err := r.Check(o, mustDecimal("10"), 2); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.halt {
+				r.Halt()
+			}
+
+			reservation, err := r.Check(tt.o, mustDecimal(tt.quantity), 2)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if reservation == nil {
+					t.Fatal("no reservation")
+				}
+				return
+			}
+
+			riskErr, ok := err.(*RiskError)
+			if !ok {
+				t.Fatalf("error = %v, want %s", err, tt.reason)
+			}
+			if riskErr.Reason != tt.reason {
+				t.Errorf("reason = %s, want %s", riskErr.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestRiskComplete(t *testing.T) {
+	pair := types.Pair{Base: "LTC", Quote: "BTC"}
+	limits := &RiskConfig{
+		MaxPairNotional: map[string]decimal.Decimal{"LTC/BTC": mustDecimal("0.1")},
+		DailyLossLimit:  map[string]decimal.Decimal{"BTC": mustDecimal("0.01")},
+	}
+
+	tests := []struct {
+		name       string
+		residual   string
+		realized   string
+		unrealized string
+		pnl        string
+		exposure   string // of the pair after completion
+		halted     bool
+	}{
+		{
+			name:       "matched",
+			residual:   "0",
+			realized:   "0.001",
+			unrealized: "0",
+			pnl:        "0.001",
+			exposure:   "0",
+		},
+		{
+			name:       "long residual holds exposure at its cost",
+			residual:   "4",
+			realized:   "0.001",
+			unrealized: "-0.002",
+			pnl:        "-0.001",
+			exposure:   "0.04",
+		},
+		{
+			name:       "short residual holds exposure at its proceeds",
+			residual:   "-4",
+			realized:   "0",
+			unrealized: "0",
+			pnl:        "0",
+			exposure:   "0.044",
+		},
+		{
+			name:       "marked loss breaches the limit",
+			residual:   "4",
+			realized:   "-0.005",
+			unrealized: "-0.006",
+			pnl:        "-0.011",
+			exposure:   "0.04",
+			halted:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRiskManager(limits)
+			o := &Opportunity{Pair: pair, BuyExchange: "a", SellExchange: "b", BuyPrice: mustDecimal("0.01")}
+			reservation, err := r.Check(o, mustDecimal("10"), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r.Complete(reservation, &Execution{
+				ID:            "execution",
+				Opportunity:   o,
+				BuyOrder:      &types.Order{AveragePrice: mustDecimal("0.01")},
+				SellOrder:     &types.Order{AveragePrice: mustDecimal("0.011")},
+				Residual:      mustDecimal(tt.residual),
+				RealizedPnL:   mustDecimal(tt.realized),
+				UnrealizedPnL: mustDecimal(tt.unrealized),
+			})
+
+			assertDecimal(t, "daily P&L", r.DailyPnL()["BTC"], tt.pnl)
+			assertDecimal(t, "pair exposure", r.pairExposure[pair], tt.exposure)
+			if r.Halted() != tt.halted {
+				t.Errorf("halted = %v, want %v", r.Halted(), tt.halted)
+			}
+
+			// Reconcile flattening the residual releases its exposure
+			r.Complete(nil, &Execution{ID: "execution", Opportunity: o, Residual: decimal.Zero})
+			assertDecimal(t, "pair exposure after reconcile", r.pairExposure[pair], "0")
+			for exchange, exposure := range r.exchangeExposure {
+				assertDecimal(t, exchange+" exposure after reconcile", exposure["BTC"], "0")
+			}
+		})
+	}
+}
+
+func TestRiskDayRollOver(t *testing.T) {
+	pair := types.Pair{Base: "LTC", Quote: "BTC"}
+	r := NewRiskManager(&RiskConfig{DailyLossLimit: map[string]decimal.Decimal{"BTC": mustDecimal("0.01")}})
+
+	o := &Opportunity{Pair: pair, BuyExchange: "a", SellExchange: "b", BuyPrice: mustDecimal("0.01")}
+	r.Complete(nil, &Execution{ID: "loss", Opportunity: o, RealizedPnL: mustDecimal("-0.02"), UnrealizedPnL: decimal.Zero})
+	if !r.Halted() {
+		t.Fatal("loss limit breach did not halt trading")
+	}
+
+	// P&L of the previous day is forgotten at UTC midnight
+	r.rollDay(time.Now().Add(24 * time.Hour))
+	if pnl := r.dailyPnL["BTC"]; pnl.Sign() != 0 {
+		t.Errorf("daily P&L after roll-over = %s, want 0", pnl)
+	}
+
+	// Halt stays until manual reset
+	if !r.Halted() {
+		t.Error("roll-over resumed trading")
+	}
+	if _, err := r.Check(o, one, 2); err == nil {
+		t.Error("trade approved while halted")
+	}
+
+	r.ResetLossLimit()
+	if r.Halted() {
+		t.Error("still halted after reset")
+	}
+	if _, err := r.Check(o, one, 2); err != nil {
+		t.Errorf("unexpected error after reset: %v", err)
+	}
+}
+
+func TestRiskCheckHedge(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxOrders int
+		halt      bool
+		orders    int // placed before the hedge
+		wantErr   bool
+	}{
+		{name: "no limit", orders: 100},
+		{name: "kill switch does not apply", halt: true},
+		{name: "under order rate", maxOrders: 3, orders: 2},
+		{name: "over order rate", maxOrders: 2, orders: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRiskManager(&RiskConfig{MaxOrdersPerMinute: tt.maxOrders})
+			o := &Opportunity{Pair: types.Pair{Base: "LTC", Quote: "BTC"}, BuyPrice: mustDecimal("0.01")}
+			if _, err := r.Check(o, one, tt.orders); err != nil {
+				t.Fatal(err)
+			}
+			if tt.halt {
+				r.Halt()
+			}
+
+			if err := r.CheckHedge(); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	one        = decimal.New(1, 0)
+	bpsPerUnit = decimal.New(10000, 0)
+)
+
+// SpreadConfig stores cross-exchange spread detector options
+type SpreadConfig struct {
+	MinNetSpreadBps decimal.Decimal // opportunities with lower net spread are ignored
+	MaxQuoteAge     time.Duration   // older quotes are considered stale, zero means no limit
+}
+
+// Opportunity is a chance to buy a pair on one exchange and sell it on another
+type Opportunity struct {
+	Pair           types.Pair
+	BuyExchange    string
+	SellExchange   string
+	BuyPrice       decimal.Decimal // best ask on the buy exchange
+	SellPrice      decimal.Decimal // best bid on the sell exchange
+	BuyTime        time.Time       // time of the buy exchange quote
+	SellTime       time.Time       // time of the sell exchange quote
+	GrossSpreadBps decimal.Decimal
+	NetSpreadBps   decimal.Decimal // after taker fees, and WithdrawalCost per unit of Quantity when both are known
+	MaxQuantity    decimal.Decimal // executable at positive net spread, zero when order books are not known
+	Quantity       decimal.Decimal // what we can afford on both sides, zero when balances are not known
+	// WithdrawalCost is what moving bought base currency and received quote currency back
+	// between the exchanges costs in quote currency, zero when withdrawal fees are not known
+	WithdrawalCost decimal.Decimal
+	DetectedAt     time.Time
+}
+
+// SpreadDetector compares best ask on one exchange against best bid on other exchanges
+type SpreadDetector struct {
+	cnf      *SpreadConfig
+	fees     *fees.Model
+	store    *TickerStore
+	books    *OrderBookStore
+	balances *BalanceStore
+	rules    *RulesStore
+}
+
+// NewSpreadDetector returns new SpreadDetector instance
+func NewSpreadDetector(cnf *SpreadConfig, feeModel *fees.Model, store *TickerStore, books *OrderBookStore, balances *BalanceStore, rules *RulesStore) *SpreadDetector {
+	return &SpreadDetector{
+		cnf:      cnf,
+		fees:     feeModel,
+		store:    store,
+		books:    books,
+		balances: balances,
+		rules:    rules,
+	}
+}
+
+// Detect checks the updated ticker against quotes for the same pair from other exchanges
+func (d *SpreadDetector) Detect(ticker *types.Ticker) []*Opportunity {
+	return d.DetectAt(ticker, time.Now())
+}
+
+// DetectAt is Detect with quote ages measured at the given time, e.g. when replaying recorded data
+func (d *SpreadDetector) DetectAt(ticker *types.Ticker, now time.Time) []*Opportunity {
+	if d.isStale(ticker, now) {
+		return nil
+	}
+
+	var opportunities []*Opportunity
+	for _, other := range d.store.GetPair(ticker.Pair) {
+		if other.Exchange == ticker.Exchange || d.isStale(other, now) {
+			continue
+		}
+
+		// Buy here and sell there, then the other way round
+		if o := d.compare(ticker, other, now); o != nil {
+			opportunities = append(opportunities, o)
+		}
+		if o := d.compare(other, ticker, now); o != nil {
+			opportunities = append(opportunities, o)
+		}
+	}
+
+	return opportunities
+}
+
+func (d *SpreadDetector) compare(buy, sell *types.Ticker, now time.Time) *Opportunity {
+	if buy.Ask.Sign() <= 0 || sell.Bid.Sign() <= 0 || !sell.Bid.GreaterThan(buy.Ask) {
+		return nil
+	}
+
+	// Effective prices after paying taker fee on both legs
+	cost := buy.Ask.Mul(one.Add(d.fees.Taker(buy.Exchange, buy.Pair)))
+	proceeds := sell.Bid.Mul(one.Sub(d.fees.Taker(sell.Exchange, sell.Pair)))
+
+	net := proceeds.Sub(cost).Div(cost).Mul(bpsPerUnit)
+	if net.LessThan(d.cnf.MinNetSpreadBps) {
+		return nil
+	}
+
+	o := &Opportunity{
+		Pair:           buy.Pair,
+		BuyExchange:    buy.Exchange,
+		SellExchange:   sell.Exchange,
+		BuyPrice:       buy.Ask,
+		SellPrice:      sell.Bid,
+		BuyTime:        buy.Time,
+		SellTime:       sell.Time,
+		GrossSpreadBps: sell.Bid.Sub(buy.Ask).Div(buy.Ask).Mul(bpsPerUnit),
+		NetSpreadBps:   net,
+		MaxQuantity:    d.maxQuantity(buy, sell, now),
+		DetectedAt:     now,
+	}
+	o.Quantity = d.affordableQuantity(o)
+	if !d.tradable(o) {
+		return nil
+	}
+	o.WithdrawalCost = d.withdrawalCost(o)
+
+	// Moving funds back costs the same however much we trade, the smaller the
+	// quantity the larger its share of every unit
+	if o.Quantity.Sign() > 0 && o.WithdrawalCost.Sign() > 0 {
+		cost = cost.Add(o.WithdrawalCost.Div(o.Quantity))
+		o.NetSpreadBps = proceeds.Sub(cost).Div(cost).Mul(bpsPerUnit)
+		if o.NetSpreadBps.LessThan(d.cnf.MinNetSpreadBps) {
+			return nil
+		}
+	}
+
+	return o
+}
+
+func (d *SpreadDetector) isStale(ticker *types.Ticker, now time.Time) bool {
+	return d.cnf.MaxQuoteAge > 0 && now.Sub(ticker.Time) > d.cnf.MaxQuoteAge
+}
+
+func (d *SpreadDetector) maxQuantity(buy, sell *types.Ticker, now time.Time) decimal.Decimal {
+	buyBook, ok := d.books.Get(buy.Exchange, buy.Pair)
+	if !ok || (d.cnf.MaxQuoteAge > 0 && now.Sub(buyBook.Time) > d.cnf.MaxQuoteAge) {
+		return decimal.Zero
+	}
+
+	sellBook, ok := d.books.Get(sell.Exchange, sell.Pair)
+	if !ok || (d.cnf.MaxQuoteAge > 0 && now.Sub(sellBook.Time) > d.cnf.MaxQuoteAge) {
+		return decimal.Zero
+	}
+
+	return ExecutableQuantity(buyBook.Asks, sellBook.Bids, d.fees.Taker(buy.Exchange, buy.Pair), d.fees.Taker(sell.Exchange, sell.Pair))
+}
+
+// affordableQuantity caps the opportunity by quote currency available on the buy
+// exchange and base currency available on the sell exchange
+func (d *SpreadDetector) affordableQuantity(o *Opportunity) decimal.Decimal {
+	if !d.balances.Known(o.BuyExchange) || !d.balances.Known(o.SellExchange) {
+		return decimal.Zero
+	}
+
+	cost := o.BuyPrice.Mul(one.Add(d.fees.Taker(o.BuyExchange, o.Pair)))
+	quantity := decimal.Min(
+		d.balances.Available(o.BuyExchange, o.Pair.Quote).Div(cost),
+		d.balances.Available(o.SellExchange, o.Pair.Base),
+	)
+
+	if o.MaxQuantity.Sign() > 0 {
+		quantity = decimal.Min(quantity, o.MaxQuantity)
+	}
+
+	return quantity
+}
+
+// tradable rounds the quantity to trading rules of both exchanges and reports
+// whether the opportunity is large enough for both of them to accept the orders
+func (d *SpreadDetector) tradable(o *Opportunity) bool {
+	for _, leg := range []struct {
+		exchange string
+		price    decimal.Decimal
+	}{
+		{o.BuyExchange, o.BuyPrice},
+		{o.SellExchange, o.SellPrice},
+	} {
+		rules, ok := d.rules.Get(leg.exchange, o.Pair)
+		if !ok {
+			continue
+		}
+		if !rules.Active {
+			return false
+		}
+
+		if o.MaxQuantity.Sign() > 0 && rules.Validate(leg.price, rules.RoundQuantity(o.MaxQuantity)) != nil {
+			return false
+		}
+
+		if o.Quantity.Sign() > 0 {
+			o.Quantity = rules.RoundQuantity(o.Quantity)
+			if rules.Validate(leg.price, o.Quantity) != nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (d *SpreadDetector) withdrawalCost(o *Opportunity) decimal.Decimal {
+	baseFee, ok := d.fees.Withdrawal(o.BuyExchange, o.Pair.Base)
+	if !ok {
+		return decimal.Zero
+	}
+
+	quoteFee, ok := d.fees.Withdrawal(o.SellExchange, o.Pair.Quote)
+	if !ok {
+		return decimal.Zero
+	}
+
+	return baseFee.Mul(o.SellPrice).Add(quoteFee)
+}
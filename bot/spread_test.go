@@ -0,0 +1,307 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// newSpreadDetector returns a detector of exchanges a and b both charging the taker fee
+func newSpreadDetector(minNetSpreadBps, taker string) *SpreadDetector {
+	rates := fees.Rates{Maker: mustDecimal(taker), Taker: mustDecimal(taker)}
+	feeModel := fees.New(&fees.Config{
+		Exchanges: map[string]*fees.Schedule{"a": {Rates: rates}, "b": {Rates: rates}},
+	})
+
+	return NewSpreadDetector(
+		&SpreadConfig{MinNetSpreadBps: mustDecimal(minNetSpreadBps), MaxQuoteAge: time.Minute},
+		feeModel,
+		NewTickerStore(),
+		NewOrderBookStore(),
+		NewBalanceStore(),
+		NewRulesStore(),
+	)
+}
+
+// quote stores a LTC/BTC ticker of the exchange
+func quote(d *SpreadDetector, exchange, bid, ask string, at time.Time) *types.Ticker {
+	ticker := &types.Ticker{Exchange: exchange, Pair: ltcBtc, Bid: mustDecimal(bid), Ask: mustDecimal(ask), Time: at}
+	d.store.Update(ticker)
+	return ticker
+}
+
+func TestSpreadDetector(t *testing.T) {
+	tests := []struct {
+		name       string
+		taker      string
+		minNet     string
+		quotes     map[string][2]string // bid and ask per exchange
+		stale      string               // exchange with an old quote
+		balances   map[string][]*types.Balance
+		withdrawal map[string]map[string]decimal.Decimal
+		rules      *types.TradingRules // of exchange b
+		want       []string            // buy->sell net spread and withdrawal cost
+	}{
+		{
+			name:   "without fees",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			want:   []string{"a->b 1000.00 0"},
+		},
+		{
+			name:   "taker fees on both legs",
+			taker:  "0.0025",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			want:   []string{"a->b 945.14 0"},
+		},
+		{
+			name:   "fees eat the spread",
+			taker:  "0.0025",
+			minNet: "0",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.01002", "0.0101"}},
+		},
+		{
+			name:   "below minimum net spread",
+			taker:  "0",
+			minNet: "100",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.01005", "0.0101"}},
+		},
+		{
+			name:   "in the other direction",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0125", "0.0126"}, "b": {"0.0099", "0.01"}},
+			want:   []string{"b->a 2500.00 0"},
+		},
+		{
+			name:   "missing quotes skipped",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0", "0"}, "b": {"0.011", "0.0111"}},
+		},
+		{
+			name:   "same exchange",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.011", "0.01"}},
+		},
+		{
+			name:   "stale quote",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			stale:  "b",
+		},
+		{
+			name:   "below minimum order size",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("2.5")}},
+			},
+			rules: &types.TradingRules{Exchange: "b", Pair: ltcBtc, MinQuantity: mustDecimal("5"), Active: true},
+		},
+		{
+			name:   "above minimum order size",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("5")}},
+			},
+			rules: &types.TradingRules{Exchange: "b", Pair: ltcBtc, MinQuantity: mustDecimal("5"), Active: true},
+			want:  []string{"a->b 1000.00 0"},
+		},
+		{
+			name:   "pair not active",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			rules:  &types.TradingRules{Exchange: "b", Pair: ltcBtc},
+		},
+		{
+			name:   "withdrawal cost without balances",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			withdrawal: map[string]map[string]decimal.Decimal{
+				"a": {"LTC": mustDecimal("0.01")},
+				"b": {"BTC": mustDecimal("0.0001")},
+			},
+			want: []string{"a->b 1000.00 0.00021"},
+		},
+		{
+			// 0.00021 BTC to move 2.5 LTC back adds 0.000084 BTC to every unit bought at 0.01
+			name:   "withdrawal cost spread over quantity",
+			taker:  "0",
+			minNet: "10",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("2.5")}},
+			},
+			withdrawal: map[string]map[string]decimal.Decimal{
+				"a": {"LTC": mustDecimal("0.01")},
+				"b": {"BTC": mustDecimal("0.0001")},
+			},
+			want: []string{"a->b 908.37 0.00021"},
+		},
+		{
+			name:   "withdrawal cost below minimum net spread",
+			taker:  "0",
+			minNet: "950",
+			quotes: map[string][2]string{"a": {"0.0099", "0.01"}, "b": {"0.011", "0.0111"}},
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("2.5")}},
+			},
+			withdrawal: map[string]map[string]decimal.Decimal{
+				"a": {"LTC": mustDecimal("0.01")},
+				"b": {"BTC": mustDecimal("0.0001")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			d := newSpreadDetector(tt.minNet, tt.taker)
+			for exchange, balances := range tt.balances {
+				d.balances.Update(exchange, balances)
+			}
+			for exchange, withdrawal := range tt.withdrawal {
+				d.fees.SetWithdrawalFees(exchange, withdrawal)
+			}
+			if tt.rules != nil {
+				d.rules.Update("b", []*types.TradingRules{tt.rules})
+			}
+
+			var updated *types.Ticker
+			for _, exchange := range []string{"a", "b"} {
+				q, ok := tt.quotes[exchange]
+				if !ok {
+					continue
+				}
+				at := now
+				if exchange == tt.stale {
+					at = now.Add(-time.Hour)
+				}
+				updated = quote(d, exchange, q[0], q[1], at)
+			}
+
+			var got []string
+			for _, o := range d.DetectAt(updated, now) {
+				if o.Pair != ltcBtc || o.NetSpreadBps.GreaterThan(o.GrossSpreadBps) {
+					t.Errorf("opportunity %+v nets more than its gross spread", o)
+				}
+				got = append(got, o.BuyExchange+"->"+o.SellExchange+" "+o.NetSpreadBps.StringFixed(2)+" "+o.WithdrawalCost.String())
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("opportunities = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("opportunity %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSpreadSizedByBalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances map[string][]*types.Balance
+		books    bool
+		step     string
+		quantity string
+	}{
+		{
+			name:     "balances unknown",
+			quantity: "0",
+		},
+		{
+			name: "balances of the sell exchange unknown",
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+			},
+			quantity: "0",
+		},
+		{
+			// 0.0401 BTC buys 4 LTC at 0.01 plus 0.25% fee
+			name: "quote currency on the buy exchange",
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("0.0401")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("10")}},
+			},
+			quantity: "4",
+		},
+		{
+			name: "base currency on the sell exchange",
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("2.5")}},
+			},
+			quantity: "2.5",
+		},
+		{
+			name: "currency not held",
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "BTC", Available: mustDecimal("1")}},
+			},
+			quantity: "0",
+		},
+		{
+			name: "order book depth",
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("10")}},
+			},
+			books:    true,
+			quantity: "1.5",
+		},
+		{
+			name: "rounded to quantity step",
+			balances: map[string][]*types.Balance{
+				"a": {{Currency: "BTC", Available: mustDecimal("1")}},
+				"b": {{Currency: "LTC", Available: mustDecimal("2.57")}},
+			},
+			step:     "0.1",
+			quantity: "2.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			d := newSpreadDetector("10", "0.0025")
+			for exchange, balances := range tt.balances {
+				d.balances.Update(exchange, balances)
+			}
+			if tt.books {
+				d.books.Update(&types.OrderBook{Exchange: "a", Pair: ltcBtc, Asks: levels("0.01", "1.5", "0.0112", "5"), Time: now})
+				d.books.Update(&types.OrderBook{Exchange: "b", Pair: ltcBtc, Bids: levels("0.011", "3"), Time: now})
+			}
+			if tt.step != "" {
+				d.rules.Update("b", []*types.TradingRules{{Exchange: "b", Pair: ltcBtc, QuantityStep: mustDecimal(tt.step), Active: true}})
+			}
+
+			quote(d, "a", "0.0099", "0.01", now)
+			opportunities := d.DetectAt(quote(d, "b", "0.011", "0.0111", now), now)
+			if len(opportunities) != 1 {
+				t.Fatalf("got %d opportunities, want 1", len(opportunities))
+			}
+			assertDecimal(t, "quantity", opportunities[0].Quantity, tt.quantity)
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package bot
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// TickerStore keeps the latest ticker per exchange and pair, safe for concurrent use
+type TickerStore struct {
+	tickers map[string]map[types.Pair]*types.Ticker
+	mu      sync.RWMutex
+}
+
+// NewTickerStore returns new TickerStore instance
+func NewTickerStore() *TickerStore {
+	return &TickerStore{
+		tickers: make(map[string]map[types.Pair]*types.Ticker),
+	}
+}
+
+// Update stores the ticker unless we already hold a more recent one
+func (s *TickerStore) Update(ticker *types.Ticker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byPair, ok := s.tickers[ticker.Exchange]
+	if !ok {
+		byPair = make(map[types.Pair]*types.Ticker)
+		s.tickers[ticker.Exchange] = byPair
+	}
+
+	if latest, ok := byPair[ticker.Pair]; ok && latest.Time.After(ticker.Time) {
+		return
+	}
+
+	byPair[ticker.Pair] = ticker
+}
+
+// Get returns the latest ticker for a pair on an exchange
+func (s *TickerStore) Get(exchange string, pair types.Pair) (*types.Ticker, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ticker, ok := s.tickers[exchange][pair]
+	return ticker, ok
+}
+
+// GetPair returns latest tickers for a pair from all exchanges quoting it, sorted by exchange name
+func (s *TickerStore) GetPair(pair types.Pair) []*types.Ticker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tickers := make([]*types.Ticker, 0, len(s.tickers))
+	for _, byPair := range s.tickers {
+		if ticker, ok := byPair[pair]; ok {
+			tickers = append(tickers, ticker)
+		}
+	}
+
+	sort.Slice(tickers, func(i, j int) bool {
+		return tickers[i].Exchange < tickers[j].Exchange
+	})
+
+	return tickers
+}
+
+// GetExchange returns latest tickers for all pairs quoted by an exchange
+func (s *TickerStore) GetExchange(exchange string) []*types.Ticker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tickers := make([]*types.Ticker, 0, len(s.tickers[exchange]))
+	for _, ticker := range s.tickers[exchange] {
+		tickers = append(tickers, ticker)
+	}
+
+	return tickers
+}
+
+// OlderThan returns tickers which have not been updated for longer than maxAge
+func (s *TickerStore) OlderThan(maxAge time.Duration) []*types.Ticker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cutoff := time.Now().Add(-maxAge)
+
+	var tickers []*types.Ticker
+	for _, byPair := range s.tickers {
+		for _, ticker := range byPair {
+			if ticker.Time.Before(cutoff) {
+				tickers = append(tickers, ticker)
+			}
+		}
+	}
+
+	return tickers
+}
+
+// OrderBookStore keeps the latest order book per exchange and pair, safe for concurrent use
+type OrderBookStore struct {
+	books map[string]map[types.Pair]*types.OrderBook
+	mu    sync.RWMutex
+}
+
+// NewOrderBookStore returns new OrderBookStore instance
+func NewOrderBookStore() *OrderBookStore {
+	return &OrderBookStore{
+		books: make(map[string]map[types.Pair]*types.OrderBook),
+	}
+}
+
+// Update stores the order book unless we already hold a more recent one
+func (s *OrderBookStore) Update(book *types.OrderBook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byPair, ok := s.books[book.Exchange]
+	if !ok {
+		byPair = make(map[types.Pair]*types.OrderBook)
+		s.books[book.Exchange] = byPair
+	}
+
+	if latest, ok := byPair[book.Pair]; ok && latest.Time.After(book.Time) {
+		return
+	}
+
+	byPair[book.Pair] = book
+}
+
+// Get returns the latest order book for a pair on an exchange
+func (s *OrderBookStore) Get(exchange string, pair types.Pair) (*types.OrderBook, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	book, ok := s.books[exchange][pair]
+	return book, ok
+}
+
+// BalanceStore keeps the latest balances per exchange and currency, safe for concurrent use
+type BalanceStore struct {
+	balances  map[string]map[string]*types.Balance
+	updatedAt map[string]time.Time
+	mu        sync.RWMutex
+}
+
+// NewBalanceStore returns new BalanceStore instance
+func NewBalanceStore() *BalanceStore {
+	return &BalanceStore{
+		balances:  make(map[string]map[string]*types.Balance),
+		updatedAt: make(map[string]time.Time),
+	}
+}
+
+// Update replaces all balances of an exchange
+func (s *BalanceStore) Update(exchange string, balances []*types.Balance) {
+	byCurrency := make(map[string]*types.Balance, len(balances))
+	for _, balance := range balances {
+		byCurrency[balance.Currency] = balance
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.balances[exchange] = byCurrency
+	s.updatedAt[exchange] = time.Now()
+}
+
+// Known returns true once balances of the exchange have been fetched
+func (s *BalanceStore) Known(exchange string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.balances[exchange]
+	return ok
+}
+
+// Available returns quantity of a currency we can spend on an exchange, zero if unknown
+func (s *BalanceStore) Available(exchange, currency string) decimal.Decimal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if balance, ok := s.balances[exchange][currency]; ok {
+		return balance.Available
+	}
+
+	return decimal.Zero
+}
+
+// GetExchange returns all balances of an exchange and time they were fetched at
+func (s *BalanceStore) GetExchange(exchange string) ([]*types.Balance, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	balances := make([]*types.Balance, 0, len(s.balances[exchange]))
+	for _, balance := range s.balances[exchange] {
+		balances = append(balances, balance)
+	}
+
+	return balances, s.updatedAt[exchange]
+}
+
+// CurrencyStore keeps transfer properties of currencies per exchange, safe for concurrent use
+type CurrencyStore struct {
+	currencies map[string]map[string]*types.Currency
+	mu         sync.RWMutex
+}
+
+// NewCurrencyStore returns new CurrencyStore instance
+func NewCurrencyStore() *CurrencyStore {
+	return &CurrencyStore{
+		currencies: make(map[string]map[string]*types.Currency),
+	}
+}
+
+// Update replaces all currencies of an exchange
+func (s *CurrencyStore) Update(exchange string, currencies []*types.Currency) {
+	byCurrency := make(map[string]*types.Currency, len(currencies))
+	for _, c := range currencies {
+		byCurrency[c.Currency] = c
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.currencies[exchange] = byCurrency
+}
+
+// Get returns transfer properties of a currency on an exchange
+func (s *CurrencyStore) Get(exchange, currency string) (*types.Currency, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.currencies[exchange][currency]
+	return c, ok
+}
+
+// Get returns balance of a currency on an exchange
+func (s *BalanceStore) Get(exchange, currency string) (*types.Balance, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	balance, ok := s.balances[exchange][currency]
+	return balance, ok
+}
+
+// RulesStore keeps trading rules per exchange and pair, safe for concurrent use
+type RulesStore struct {
+	rules map[string]map[types.Pair]*types.TradingRules
+	mu    sync.RWMutex
+}
+
+// NewRulesStore returns new RulesStore instance
+func NewRulesStore() *RulesStore {
+	return &RulesStore{
+		rules: make(map[string]map[types.Pair]*types.TradingRules),
+	}
+}
+
+// Update replaces all trading rules of an exchange
+func (s *RulesStore) Update(exchange string, rules []*types.TradingRules) {
+	byPair := make(map[types.Pair]*types.TradingRules, len(rules))
+	for _, r := range rules {
+		byPair[r.Pair] = r
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules[exchange] = byPair
+}
+
+// Get returns trading rules of a pair on an exchange
+func (s *RulesStore) Get(exchange string, pair types.Pair) (*types.TradingRules, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.rules[exchange][pair]
+	return r, ok
+}
+
+// RoundPrice rounds the price to the tick of the exchange, unchanged when rules are not known
+func (s *RulesStore) RoundPrice(exchange string, pair types.Pair, side types.Side, price decimal.Decimal) decimal.Decimal {
+	if r, ok := s.Get(exchange, pair); ok {
+		return r.RoundPrice(price, side)
+	}
+	return price
+}
+
+// RoundQuantity rounds the quantity down to the step of the exchange, unchanged when rules are not known
+func (s *RulesStore) RoundQuantity(exchange string, pair types.Pair, quantity decimal.Decimal) decimal.Decimal {
+	if r, ok := s.Get(exchange, pair); ok {
+		return r.RoundQuantity(quantity)
+	}
+	return quantity
+}
+
+// Validate checks the order against rules of the exchange, any order passes when rules are not known
+func (s *RulesStore) Validate(exchange string, pair types.Pair, price, quantity decimal.Decimal) error {
+	if r, ok := s.Get(exchange, pair); ok {
+		return r.Validate(price, quantity)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var ltcBtc = types.Pair{Base: "LTC", Quote: "BTC"}
+
+func TestTickerStore(t *testing.T) {
+	s := NewTickerStore()
+	now := time.Now()
+	eth := types.Pair{Base: "ETH", Quote: "BTC"}
+
+	s.Update(&types.Ticker{Exchange: "poloniex", Pair: ltcBtc, Bid: mustDecimal("2"), Time: now})
+	s.Update(&types.Ticker{Exchange: "bittrex", Pair: ltcBtc, Bid: mustDecimal("1"), Time: now})
+	s.Update(&types.Ticker{Exchange: "bittrex", Pair: eth, Bid: mustDecimal("3"), Time: now.Add(-time.Hour)})
+
+	// Tickers arriving out of order do not replace more recent ones
+	s.Update(&types.Ticker{Exchange: "bittrex", Pair: ltcBtc, Bid: mustDecimal("0.5"), Time: now.Add(-time.Second)})
+	if ticker, ok := s.Get("bittrex", ltcBtc); !ok || !ticker.Bid.Equal(mustDecimal("1")) {
+		t.Errorf("bittrex LTC/BTC = %+v, want bid 1", ticker)
+	}
+	s.Update(&types.Ticker{Exchange: "bittrex", Pair: ltcBtc, Bid: mustDecimal("1.5"), Time: now})
+	if ticker, _ := s.Get("bittrex", ltcBtc); !ticker.Bid.Equal(mustDecimal("1.5")) {
+		t.Errorf("bittrex LTC/BTC bid = %s, want 1.5 from the same time", ticker.Bid)
+	}
+	if _, ok := s.Get("kraken", ltcBtc); ok {
+		t.Error("kraken LTC/BTC found")
+	}
+
+	pair := s.GetPair(ltcBtc)
+	if len(pair) != 2 || pair[0].Exchange != "bittrex" || pair[1].Exchange != "poloniex" {
+		t.Errorf("LTC/BTC tickers = %v, want bittrex and poloniex", pair)
+	}
+	if exchange := s.GetExchange("bittrex"); len(exchange) != 2 {
+		t.Errorf("got %d bittrex tickers, want 2", len(exchange))
+	}
+	if old := s.OlderThan(time.Minute); len(old) != 1 || old[0].Pair != eth {
+		t.Errorf("stale tickers = %v, want ETH/BTC", old)
+	}
+}
+
+func TestTickerStoreConcurrent(t *testing.T) {
+	const (
+		exchanges = 4
+		updates   = 500
+	)
+
+	s := NewTickerStore()
+	start := time.Now()
+
+	// Readers query the store while it is updated, run with -race
+	var readers sync.WaitGroup
+	for i := 0; i < exchanges; i++ {
+		readers.Add(1)
+		go func(exchange string) {
+			defer readers.Done()
+
+			for j := 0; j < updates; j++ {
+				for _, ticker := range s.GetPair(ltcBtc) {
+					if ticker.Time.Before(start) {
+						t.Errorf("%s ticker from before the first update", ticker.Exchange)
+					}
+				}
+				s.Get(exchange, ltcBtc)
+				s.GetExchange(exchange)
+				s.OlderThan(time.Hour)
+			}
+		}(fmt.Sprintf("exchange%d", i))
+	}
+
+	// Two writers per exchange race each other with interleaved times
+	var writers sync.WaitGroup
+	for i := 0; i < exchanges; i++ {
+		for offset := 0; offset < 2; offset++ {
+			writers.Add(1)
+			go func(exchange string, offset int) {
+				defer writers.Done()
+
+				for j := offset; j < updates; j += 2 {
+					s.Update(&types.Ticker{
+						Exchange: exchange,
+						Pair:     ltcBtc,
+						Bid:      mustDecimal(fmt.Sprint(j)),
+						Time:     start.Add(time.Duration(j) * time.Millisecond),
+					})
+				}
+			}(fmt.Sprintf("exchange%d", i), offset)
+		}
+	}
+	writers.Wait()
+	readers.Wait()
+
+	// Whatever the interleaving, the most recent ticker wins
+	tickers := s.GetPair(ltcBtc)
+	if len(tickers) != exchanges {
+		t.Fatalf("got %d tickers, want %d", len(tickers), exchanges)
+	}
+	for _, ticker := range tickers {
+		assertDecimal(t, ticker.Exchange+" bid", ticker.Bid, fmt.Sprint(updates-1))
+	}
+}
+
+func TestBalanceStore(t *testing.T) {
+	s := NewBalanceStore()
+	if s.Known("bittrex") {
+		t.Error("balances known before the first update")
+	}
+	assertDecimal(t, "unknown available", s.Available("bittrex", "BTC"), "0")
+
+	before := time.Now()
+	s.Update("bittrex", []*types.Balance{
+		{Currency: "BTC", Available: mustDecimal("1.5"), Total: mustDecimal("2")},
+		{Currency: "LTC", Available: mustDecimal("10"), Total: mustDecimal("10")},
+	})
+
+	if !s.Known("bittrex") || s.Known("poloniex") {
+		t.Errorf("known = %v/%v, want only bittrex", s.Known("bittrex"), s.Known("poloniex"))
+	}
+	assertDecimal(t, "BTC available", s.Available("bittrex", "BTC"), "1.5")
+	assertDecimal(t, "ETH available", s.Available("bittrex", "ETH"), "0")
+	if balance, ok := s.Get("bittrex", "BTC"); !ok || !balance.Total.Equal(mustDecimal("2")) {
+		t.Errorf("BTC balance = %+v, want total 2", balance)
+	}
+	if _, ok := s.Get("bittrex", "ETH"); ok {
+		t.Error("ETH balance found")
+	}
+
+	balances, updatedAt := s.GetExchange("bittrex")
+	if len(balances) != 2 || updatedAt.Before(before) {
+		t.Errorf("got %d balances updated at %s, want 2 updated after %s", len(balances), updatedAt, before)
+	}
+
+	// An update replaces all balances, currencies no longer reported are gone
+	s.Update("bittrex", []*types.Balance{{Currency: "LTC", Available: mustDecimal("4")}})
+	assertDecimal(t, "BTC available after update", s.Available("bittrex", "BTC"), "0")
+	assertDecimal(t, "LTC available after update", s.Available("bittrex", "LTC"), "4")
+
+	// Fetched without any balance the exchange is known to hold nothing
+	s.Update("poloniex", nil)
+	if !s.Known("poloniex") {
+		t.Error("poloniex not known after update")
+	}
+}
+
+func mustDecimal(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+
+	if expected := mustDecimal(want); !got.Equal(expected) {
+		t.Errorf("%s = %s, want %s", name, got, expected)
+	}
+}
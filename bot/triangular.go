@@ -0,0 +1,206 @@
+package bot
+
+import (
+	"sort"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// TriangularConfig stores triangular arbitrage detector options
+type TriangularConfig struct {
+	Exchange     string          // name of the exchange to look for cycles on
+	Markets      []types.Pair    // markets traded on the exchange
+	MinReturnBps decimal.Decimal // opportunities with lower net return are ignored
+	MaxQuoteAge  time.Duration   // older quotes are considered stale, zero means no limit
+}
+
+// Leg is a single conversion step of a multi-leg opportunity
+type Leg struct {
+	Exchange string
+	Pair     types.Pair
+	Side     types.Side
+	From     string          // currency we give
+	To       string          // currency we get
+	Price    decimal.Decimal // ask when buying, bid when selling
+	Time     time.Time       // time of the quote
+}
+
+// Rate returns how many units of To currency we get for one unit of From currency
+func (l *Leg) Rate() decimal.Decimal {
+	if l.Side == types.Buy {
+		return one.Div(l.Price)
+	}
+	return l.Price
+}
+
+// TriangularOpportunity is a profitable 3 leg cycle on a single exchange
+type TriangularOpportunity struct {
+	Exchange       string
+	Legs           []*Leg
+	GrossReturnBps decimal.Decimal
+	NetReturnBps   decimal.Decimal
+	DetectedAt     time.Time
+}
+
+// triangle is a cycle of 3 currencies, e.g. USDT -> BTC -> LTC -> USDT
+type triangle struct {
+	currencies [3]string
+	markets    [3]types.Pair
+}
+
+// TriangularDetector recomputes implied return of 3 leg cycles whenever one of their markets changes
+type TriangularDetector struct {
+	cnf      *TriangularConfig
+	fees     *fees.Model
+	store    *TickerStore
+	byMarket map[types.Pair][]*triangle
+}
+
+// NewTriangularDetector builds the currency graph from configured markets and enumerates all cycles
+func NewTriangularDetector(cnf *TriangularConfig, feeModel *fees.Model, store *TickerStore) *TriangularDetector {
+	d := &TriangularDetector{
+		cnf:      cnf,
+		fees:     feeModel,
+		store:    store,
+		byMarket: make(map[types.Pair][]*triangle),
+	}
+
+	for _, t := range findTriangles(cnf.Markets) {
+		for _, m := range t.markets {
+			d.byMarket[m] = append(d.byMarket[m], t)
+		}
+	}
+
+	return d
+}
+
+// Detect recomputes all cycles going through the updated market
+func (d *TriangularDetector) Detect(ticker *types.Ticker) []*TriangularOpportunity {
+	return d.DetectAt(ticker, time.Now())
+}
+
+// DetectAt is Detect with quote ages measured at the given time, e.g. when replaying recorded data
+func (d *TriangularDetector) DetectAt(ticker *types.Ticker, now time.Time) []*TriangularOpportunity {
+	if ticker.Exchange != d.cnf.Exchange {
+		return nil
+	}
+
+	var opportunities []*TriangularOpportunity
+	for _, t := range d.byMarket[ticker.Pair] {
+		if o := d.evaluate(t, now); o != nil {
+			opportunities = append(opportunities, o)
+		}
+	}
+
+	return opportunities
+}
+
+func (d *TriangularDetector) evaluate(t *triangle, now time.Time) *TriangularOpportunity {
+	legs := make([]*Leg, 0, len(t.markets))
+	gross := one
+	for i, market := range t.markets {
+		ticker, ok := d.store.Get(d.cnf.Exchange, market)
+		if !ok || (d.cnf.MaxQuoteAge > 0 && now.Sub(ticker.Time) > d.cnf.MaxQuoteAge) {
+			return nil
+		}
+
+		leg := newLeg(ticker, t.currencies[i], t.currencies[(i+1)%len(t.currencies)])
+		if leg.Price.Sign() <= 0 {
+			return nil
+		}
+
+		legs = append(legs, leg)
+		gross = gross.Mul(leg.Rate())
+	}
+
+	// Taker fee is paid on each leg
+	net := gross
+	for _, leg := range legs {
+		net = net.Mul(one.Sub(d.fees.Taker(leg.Exchange, leg.Pair)))
+	}
+
+	netBps := net.Sub(one).Mul(bpsPerUnit)
+	if netBps.LessThan(d.cnf.MinReturnBps) {
+		return nil
+	}
+
+	return &TriangularOpportunity{
+		Exchange:       d.cnf.Exchange,
+		Legs:           legs,
+		GrossReturnBps: gross.Sub(one).Mul(bpsPerUnit),
+		NetReturnBps:   netBps,
+		DetectedAt:     now,
+	}
+}
+
+// newLeg returns a leg converting from one currency to another using the ticker,
+// buying base currency at ask or selling it at bid depending on the direction
+func newLeg(ticker *types.Ticker, from, to string) *Leg {
+	leg := &Leg{
+		Exchange: ticker.Exchange,
+		Pair:     ticker.Pair,
+		From:     from,
+		To:       to,
+		Time:     ticker.Time,
+	}
+
+	if ticker.Pair.Quote == from {
+		leg.Side = types.Buy
+		leg.Price = ticker.Ask
+	} else {
+		leg.Side = types.Sell
+		leg.Price = ticker.Bid
+	}
+
+	return leg
+}
+
+// findTriangles enumerates all 3 leg cycles in both directions, each cycle starts
+// from its alphabetically first currency so rotations of the same cycle are not repeated
+func findTriangles(markets []types.Pair) []*triangle {
+	edges := make(map[string]map[string]types.Pair)
+	addEdge := func(from, to string, market types.Pair) {
+		if _, ok := edges[from]; !ok {
+			edges[from] = make(map[string]types.Pair)
+		}
+		edges[from][to] = market
+	}
+
+	for _, m := range markets {
+		addEdge(m.Base, m.Quote, m)
+		addEdge(m.Quote, m.Base, m)
+	}
+
+	currencies := make([]string, 0, len(edges))
+	for c := range edges {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+
+	var triangles []*triangle
+	for _, a := range currencies {
+		for b, ab := range edges[a] {
+			if b <= a {
+				continue
+			}
+			for c, bc := range edges[b] {
+				if c <= a || c == b {
+					continue
+				}
+				ca, ok := edges[c][a]
+				if !ok {
+					continue
+				}
+				triangles = append(triangles, &triangle{
+					currencies: [3]string{a, b, c},
+					markets:    [3]types.Pair{ab, bc, ca},
+				})
+			}
+		}
+	}
+
+	return triangles
+}
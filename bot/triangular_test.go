@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/fees"
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+var (
+	ethBtc = types.Pair{Base: "ETH", Quote: "BTC"}
+	ltcEth = types.Pair{Base: "LTC", Quote: "ETH"}
+)
+
+func TestFindTriangles(t *testing.T) {
+	xmrBtc := types.Pair{Base: "XMR", Quote: "BTC"}
+
+	var got []string
+	for _, triangle := range findTriangles([]types.Pair{ltcBtc, ethBtc, ltcEth, xmrBtc}) {
+		got = append(got, strings.Join(triangle.currencies[:], "->")+" via "+
+			triangle.markets[0].String()+", "+triangle.markets[1].String()+", "+triangle.markets[2].String())
+	}
+	sort.Strings(got)
+
+	// Each cycle is found once in both directions, markets without a cycle are left out
+	want := []string{
+		"BTC->ETH->LTC via ETH/BTC, LTC/ETH, LTC/BTC",
+		"BTC->LTC->ETH via LTC/BTC, LTC/ETH, ETH/BTC",
+	}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("triangles = %v, want %v", got, want)
+	}
+}
+
+func TestNewLeg(t *testing.T) {
+	ticker := &types.Ticker{Exchange: "a", Pair: ltcBtc, Bid: mustDecimal("0.0099"), Ask: mustDecimal("0.01")}
+
+	// Giving quote currency buys base currency at ask
+	buy := newLeg(ticker, "BTC", "LTC")
+	if buy.Side != types.Buy {
+		t.Errorf("BTC->LTC side = %s, want buy", buy.Side)
+	}
+	assertDecimal(t, "buy price", buy.Price, "0.01")
+	assertDecimal(t, "buy rate", buy.Rate(), "100")
+
+	// Giving base currency sells it at bid
+	sell := newLeg(ticker, "LTC", "BTC")
+	if sell.Side != types.Sell {
+		t.Errorf("LTC->BTC side = %s, want sell", sell.Side)
+	}
+	assertDecimal(t, "sell price", sell.Price, "0.0099")
+	assertDecimal(t, "sell rate", sell.Rate(), "0.0099")
+}
+
+func TestTriangularDetector(t *testing.T) {
+	// BTC buys 20 ETH, ETH buys 5 LTC each and LTC sells for 0.011 BTC, 1.1 BTC for every BTC
+	quotes := map[types.Pair][2]string{
+		ethBtc: {"0.049", "0.05"},
+		ltcEth: {"0.19", "0.2"},
+		ltcBtc: {"0.011", "0.0111"},
+	}
+
+	tests := []struct {
+		name      string
+		taker     string
+		minReturn string
+		missing   types.Pair // market without a quote
+		stale     types.Pair // market with an old quote
+		zero      types.Pair // market quoted at zero
+		exchange  string     // of the updated ticker
+		want      []string
+	}{
+		{
+			name:      "without fees",
+			taker:     "0",
+			minReturn: "10",
+			want:      []string{"buy ETH/BTC, buy LTC/ETH, sell LTC/BTC: 1000.00 gross 1000.00 net"},
+		},
+		{
+			name:      "taker fee on every leg",
+			taker:     "0.001",
+			minReturn: "10",
+			want:      []string{"buy ETH/BTC, buy LTC/ETH, sell LTC/BTC: 1000.00 gross 967.03 net"},
+		},
+		{
+			name:      "fees eat the return",
+			taker:     "0.04",
+			minReturn: "0",
+		},
+		{
+			name:      "below minimum return",
+			taker:     "0",
+			minReturn: "1500",
+		},
+		{
+			name:      "market not quoted",
+			taker:     "0",
+			minReturn: "10",
+			missing:   ltcEth,
+		},
+		{
+			name:      "stale quote",
+			taker:     "0",
+			minReturn: "10",
+			stale:     ethBtc,
+		},
+		{
+			name:      "zero quote",
+			taker:     "0",
+			minReturn: "10",
+			zero:      ltcEth,
+		},
+		{
+			name:      "ticker of another exchange",
+			taker:     "0",
+			minReturn: "10",
+			exchange:  "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			rates := fees.Rates{Maker: mustDecimal(tt.taker), Taker: mustDecimal(tt.taker)}
+			store := NewTickerStore()
+			d := NewTriangularDetector(&TriangularConfig{
+				Exchange:     "a",
+				Markets:      []types.Pair{ltcBtc, ethBtc, ltcEth},
+				MinReturnBps: mustDecimal(tt.minReturn),
+				MaxQuoteAge:  time.Minute,
+			}, fees.New(&fees.Config{Exchanges: map[string]*fees.Schedule{"a": {Rates: rates}}}), store)
+
+			for pair, q := range quotes {
+				if pair == tt.missing {
+					continue
+				}
+				ticker := &types.Ticker{Exchange: "a", Pair: pair, Bid: mustDecimal(q[0]), Ask: mustDecimal(q[1]), Time: now}
+				if pair == tt.stale {
+					ticker.Time = now.Add(-time.Hour)
+				}
+				if pair == tt.zero {
+					ticker.Bid, ticker.Ask = mustDecimal("0"), mustDecimal("0")
+				}
+				store.Update(ticker)
+			}
+
+			updated, _ := store.Get("a", ltcBtc)
+			if tt.exchange != "" {
+				other := *updated
+				other.Exchange = tt.exchange
+				updated = &other
+			}
+
+			var got []string
+			for _, o := range d.DetectAt(updated, now) {
+				if o.Exchange != "a" || !o.DetectedAt.Equal(now) {
+					t.Errorf("opportunity on %s detected at %s", o.Exchange, o.DetectedAt)
+				}
+				legs := make([]string, len(o.Legs))
+				for i, leg := range o.Legs {
+					legs[i] = string(leg.Side) + " " + leg.Pair.String()
+				}
+				got = append(got, strings.Join(legs, ", ")+": "+o.GrossReturnBps.StringFixed(2)+" gross "+o.NetReturnBps.StringFixed(2)+" net")
+			}
+
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("opportunities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
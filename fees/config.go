@@ -0,0 +1,58 @@
+package fees
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/shopspring/decimal"
+)
+
+// Rates of maker and taker commission, e.g. 0.0025 for 0.25%
+type Rates struct {
+	Maker decimal.Decimal `json:"maker"`
+	Taker decimal.Decimal `json:"taker"`
+}
+
+// Tier applies when 30 day trading volume is at least MinVolume
+type Tier struct {
+	MinVolume decimal.Decimal `json:"min_volume"`
+	Rates
+}
+
+// Schedule stores fees of a single exchange
+type Schedule struct {
+	Rates                                 // applies unless overridden by a tier or a pair
+	Tiers      []*Tier                    `json:"tiers"`
+	Pairs      map[string]*Rates          `json:"pairs"`      // keyed by pair, e.g. LTC/BTC
+	Withdrawal map[string]decimal.Decimal `json:"withdrawal"` // fixed withdrawal fee keyed by currency
+}
+
+// Config stores fee schedules keyed by exchange name
+type Config struct {
+	Exchanges map[string]*Schedule `json:"exchanges"`
+}
+
+// Override changes parts of a default schedule, fields left out keep their defaults
+type Override struct {
+	Maker      *decimal.Decimal           `json:"maker"`
+	Taker      *decimal.Decimal           `json:"taker"`
+	Tiers      []*Tier                    `json:"tiers"`      // replace default tiers when given
+	Pairs      map[string]*Rates          `json:"pairs"`      // merged into default pair overrides
+	Withdrawal map[string]decimal.Decimal `json:"withdrawal"` // merged into default withdrawal fees
+	Volume     *decimal.Decimal           `json:"volume"`     // 30 day trading volume used to pick a tier
+}
+
+// OverrideConfig stores overrides of default schedules keyed by exchange name
+type OverrideConfig struct {
+	Exchanges map[string]*Override `json:"exchanges"`
+}
+
+// ReadConfig decodes JSON overrides, e.g. {"exchanges": {"bittrex": {"taker": "0.002", "volume": "150"}}}
+func ReadConfig(r io.Reader) (*OverrideConfig, error) {
+	cnf := new(OverrideConfig)
+	if err := json.NewDecoder(r).Decode(cnf); err != nil {
+		return nil, err
+	}
+
+	return cnf, nil
+}
@@ -0,0 +1,194 @@
+// Package fees models trading and withdrawal fees of each exchange
+package fees
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrUnknownExchange is returned when overriding fees of an exchange without a default schedule
+	ErrUnknownExchange = errors.New("No fee schedule to override")
+	// ErrEmptyOverride is returned when an override of an exchange or a pair is null
+	ErrEmptyOverride = errors.New("Empty fee override")
+)
+
+// Model resolves fee rates of exchanges, safe for concurrent use
+type Model struct {
+	schedules  map[string]*Schedule
+	volumes    map[string]decimal.Decimal
+	withdrawal map[string]map[string]decimal.Decimal
+	unknown    map[string]bool // exchanges without a schedule we already warned about
+	mu         sync.RWMutex
+}
+
+// New returns new Model instance
+func New(cnf *Config) *Model {
+	m := &Model{
+		schedules:  make(map[string]*Schedule),
+		volumes:    make(map[string]decimal.Decimal),
+		withdrawal: make(map[string]map[string]decimal.Decimal),
+		unknown:    make(map[string]bool),
+	}
+
+	m.Load(cnf)
+
+	return m
+}
+
+// Load replaces schedules of configured exchanges, configured withdrawal fees
+// take precedence over ones fetched from the exchange
+func (m *Model) Load(cnf *Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for exchange, schedule := range cnf.Exchanges {
+		m.schedules[exchange] = schedule
+	}
+}
+
+// Override merges overrides into schedules of exchanges, an exchange without a
+// schedule is rejected so a typo does not leave fees of the real one at zero,
+// nothing is applied when any override is rejected
+func (m *Model) Override(cnf *OverrideConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for exchange, o := range cnf.Exchanges {
+		if _, ok := m.schedules[exchange]; !ok {
+			return fmt.Errorf("%v: %s", ErrUnknownExchange, exchange)
+		}
+		if o == nil {
+			return fmt.Errorf("%v: %s", ErrEmptyOverride, exchange)
+		}
+		for pair, rates := range o.Pairs {
+			if rates == nil {
+				return fmt.Errorf("%v: %s %s", ErrEmptyOverride, exchange, pair)
+			}
+		}
+	}
+
+	for exchange, o := range cnf.Exchanges {
+		// Schedules are replaced, never changed in place, so rates can be read without the lock
+		m.schedules[exchange] = merge(m.schedules[exchange], o)
+		if o.Volume != nil {
+			m.volumes[exchange] = *o.Volume
+		}
+	}
+
+	return nil
+}
+
+// Maker returns maker fee rate of the pair on the exchange, zero for unknown exchanges
+func (m *Model) Maker(exchange string, pair types.Pair) decimal.Decimal {
+	return m.rates(exchange, pair).Maker
+}
+
+// Taker returns taker fee rate of the pair on the exchange, zero for unknown exchanges
+func (m *Model) Taker(exchange string, pair types.Pair) decimal.Decimal {
+	return m.rates(exchange, pair).Taker
+}
+
+// Withdrawal returns fixed fee for withdrawing the currency from the exchange
+func (m *Model) Withdrawal(exchange, currency string) (decimal.Decimal, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if schedule, ok := m.schedules[exchange]; ok {
+		if fee, ok := schedule.Withdrawal[currency]; ok {
+			return fee, true
+		}
+	}
+
+	fee, ok := m.withdrawal[exchange][currency]
+	return fee, ok
+}
+
+// SetWithdrawalFees sets withdrawal fees reported by the exchange keyed by currency
+func (m *Model) SetWithdrawalFees(exchange string, withdrawal map[string]decimal.Decimal) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.withdrawal[exchange] = withdrawal
+}
+
+// rates resolves pair override first, then the highest tier reached by volume, then default rates
+func (m *Model) rates(exchange string, pair types.Pair) Rates {
+	m.mu.RLock()
+	schedule, ok := m.schedules[exchange]
+	volume := m.volumes[exchange]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.warnUnknown(exchange)
+		return Rates{}
+	}
+
+	if rates, ok := schedule.Pairs[pair.String()]; ok {
+		return *rates
+	}
+
+	rates := schedule.Rates
+	var reached *Tier
+	for _, tier := range schedule.Tiers {
+		if volume.LessThan(tier.MinVolume) {
+			continue
+		}
+		if reached == nil || tier.MinVolume.GreaterThan(reached.MinVolume) {
+			reached = tier
+		}
+	}
+	if reached != nil {
+		rates = reached.Rates
+	}
+
+	return rates
+}
+
+func (m *Model) warnUnknown(exchange string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.unknown[exchange] {
+		m.unknown[exchange] = true
+		log.Printf("[%s] No fee schedule, trading fees are assumed to be zero", exchange)
+	}
+}
+
+// merge returns a copy of the schedule with the override applied
+func merge(schedule *Schedule, o *Override) *Schedule {
+	merged := &Schedule{
+		Rates:      schedule.Rates,
+		Tiers:      schedule.Tiers,
+		Pairs:      make(map[string]*Rates, len(schedule.Pairs)+len(o.Pairs)),
+		Withdrawal: make(map[string]decimal.Decimal, len(schedule.Withdrawal)+len(o.Withdrawal)),
+	}
+
+	if o.Maker != nil {
+		merged.Maker = *o.Maker
+	}
+	if o.Taker != nil {
+		merged.Taker = *o.Taker
+	}
+	if o.Tiers != nil {
+		merged.Tiers = o.Tiers
+	}
+
+	for _, pairs := range []map[string]*Rates{schedule.Pairs, o.Pairs} {
+		for pair, rates := range pairs {
+			merged.Pairs[pair] = rates
+		}
+	}
+	for _, withdrawal := range []map[string]decimal.Decimal{schedule.Withdrawal, o.Withdrawal} {
+		for currency, fee := range withdrawal {
+			merged.Withdrawal[currency] = fee
+		}
+	}
+
+	return merged
+}
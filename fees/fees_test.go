@@ -0,0 +1,278 @@
+package fees
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ltc = types.Pair{Base: "LTC", Quote: "BTC"}
+	eth = types.Pair{Base: "ETH", Quote: "BTC"}
+)
+
+func newModel() *Model {
+	return New(&Config{
+		Exchanges: map[string]*Schedule{
+			"bittrex": {
+				Rates: Rates{Maker: mustDecimal("0.0025"), Taker: mustDecimal("0.0025")},
+			},
+			"poloniex": {
+				Rates: Rates{Maker: mustDecimal("0.0015"), Taker: mustDecimal("0.0025")},
+				Tiers: []*Tier{
+					{MinVolume: mustDecimal("600"), Rates: Rates{Maker: mustDecimal("0.0014"), Taker: mustDecimal("0.0024")}},
+					{MinVolume: mustDecimal("1200"), Rates: Rates{Maker: mustDecimal("0.0012"), Taker: mustDecimal("0.0022")}},
+				},
+				Pairs:      map[string]*Rates{"ETH/BTC": {Maker: mustDecimal("0.001"), Taker: mustDecimal("0.002")}},
+				Withdrawal: map[string]decimal.Decimal{"BTC": mustDecimal("0.0001")},
+			},
+		},
+	})
+}
+
+func TestRates(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string // JSON overrides, none when empty
+		exchange string
+		pair     types.Pair
+		maker    string
+		taker    string
+	}{
+		{
+			name:     "default rates",
+			exchange: "bittrex",
+			pair:     ltc,
+			maker:    "0.0025",
+			taker:    "0.0025",
+		},
+		{
+			name:     "unknown exchange",
+			exchange: "unknown",
+			pair:     ltc,
+			maker:    "0",
+			taker:    "0",
+		},
+		{
+			name:     "pair rates",
+			exchange: "poloniex",
+			pair:     eth,
+			maker:    "0.001",
+			taker:    "0.002",
+		},
+		{
+			name:     "volume below first tier",
+			override: `{"exchanges": {"poloniex": {"volume": "599"}}}`,
+			exchange: "poloniex",
+			pair:     ltc,
+			maker:    "0.0015",
+			taker:    "0.0025",
+		},
+		{
+			name:     "volume reaches first tier",
+			override: `{"exchanges": {"poloniex": {"volume": "600"}}}`,
+			exchange: "poloniex",
+			pair:     ltc,
+			maker:    "0.0014",
+			taker:    "0.0024",
+		},
+		{
+			name:     "highest tier reached",
+			override: `{"exchanges": {"poloniex": {"volume": "5000"}}}`,
+			exchange: "poloniex",
+			pair:     ltc,
+			maker:    "0.0012",
+			taker:    "0.0022",
+		},
+		{
+			name:     "pair rates take precedence over tiers",
+			override: `{"exchanges": {"poloniex": {"volume": "5000"}}}`,
+			exchange: "poloniex",
+			pair:     eth,
+			maker:    "0.001",
+			taker:    "0.002",
+		},
+		{
+			name:     "taker override keeps default maker",
+			override: `{"exchanges": {"bittrex": {"taker": "0.002"}}}`,
+			exchange: "bittrex",
+			pair:     ltc,
+			maker:    "0.0025",
+			taker:    "0.002",
+		},
+		{
+			name:     "pair override merged with default pairs",
+			override: `{"exchanges": {"poloniex": {"pairs": {"LTC/BTC": {"maker": "0", "taker": "0.001"}}}}}`,
+			exchange: "poloniex",
+			pair:     eth,
+			maker:    "0.001",
+			taker:    "0.002",
+		},
+		{
+			name:     "pair override",
+			override: `{"exchanges": {"poloniex": {"pairs": {"LTC/BTC": {"maker": "0", "taker": "0.001"}}}}}`,
+			exchange: "poloniex",
+			pair:     ltc,
+			maker:    "0",
+			taker:    "0.001",
+		},
+		{
+			name:     "tiers replaced",
+			override: `{"exchanges": {"poloniex": {"volume": "700", "tiers": [{"min_volume": "650", "maker": "0.0013", "taker": "0.0023"}]}}}`,
+			exchange: "poloniex",
+			pair:     ltc,
+			maker:    "0.0013",
+			taker:    "0.0023",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newModel()
+			if tt.override != "" {
+				cnf, err := ReadConfig(strings.NewReader(tt.override))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := m.Override(cnf); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			assertDecimal(t, "maker", m.Maker(tt.exchange, tt.pair), tt.maker)
+			assertDecimal(t, "taker", m.Taker(tt.exchange, tt.pair), tt.taker)
+		})
+	}
+}
+
+func TestOverrideRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		err      error
+	}{
+		{
+			name:     "unknown exchange",
+			override: `{"exchanges": {"bitrex": {"taker": "0"}, "bittrex": {"taker": "0"}}}`,
+			err:      ErrUnknownExchange,
+		},
+		{
+			name:     "null exchange",
+			override: `{"exchanges": {"bittrex": null, "poloniex": {"taker": "0"}}}`,
+			err:      ErrEmptyOverride,
+		},
+		{
+			name:     "null pair",
+			override: `{"exchanges": {"bittrex": {"taker": "0", "pairs": {"LTC/BTC": null}}}}`,
+			err:      ErrEmptyOverride,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newModel()
+			cnf, err := ReadConfig(strings.NewReader(tt.override))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = m.Override(cnf)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err.Error()) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+
+			// Nothing is applied when any override is rejected
+			assertDecimal(t, "bittrex taker", m.Taker("bittrex", ltc), "0.0025")
+			assertDecimal(t, "poloniex taker", m.Taker("poloniex", ltc), "0.0025")
+		})
+	}
+}
+
+func TestWithdrawal(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		exchange string
+		currency string
+		fee      string
+		ok       bool
+	}{
+		{
+			name:     "fetched from exchange",
+			exchange: "bittrex",
+			currency: "LTC",
+			fee:      "0.01",
+			ok:       true,
+		},
+		{
+			name:     "configured fee takes precedence",
+			exchange: "poloniex",
+			currency: "BTC",
+			fee:      "0.0001",
+			ok:       true,
+		},
+		{
+			name:     "override merged with default fees",
+			override: `{"exchanges": {"poloniex": {"withdrawal": {"LTC": "0.002"}}}}`,
+			exchange: "poloniex",
+			currency: "BTC",
+			fee:      "0.0001",
+			ok:       true,
+		},
+		{
+			name:     "override",
+			override: `{"exchanges": {"bittrex": {"withdrawal": {"LTC": "0.002"}}}}`,
+			exchange: "bittrex",
+			currency: "LTC",
+			fee:      "0.002",
+			ok:       true,
+		},
+		{
+			name:     "unknown",
+			exchange: "bittrex",
+			currency: "ETH",
+			fee:      "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newModel()
+			m.SetWithdrawalFees("bittrex", map[string]decimal.Decimal{"LTC": mustDecimal("0.01")})
+			m.SetWithdrawalFees("poloniex", map[string]decimal.Decimal{"BTC": mustDecimal("0.0005")})
+			if tt.override != "" {
+				cnf, err := ReadConfig(strings.NewReader(tt.override))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := m.Override(cnf); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fee, ok := m.Withdrawal(tt.exchange, tt.currency)
+			if ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+			assertDecimal(t, "fee", fee, tt.fee)
+		})
+	}
+}
+
+func mustDecimal(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+
+	if expected := mustDecimal(want); !got.Equal(expected) {
+		t.Errorf("%s = %s, want %s", name, got, expected)
+	}
+}
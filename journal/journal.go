@@ -0,0 +1,340 @@
+// Package journal records every order sent to an exchange so a restart can reconcile what was left open
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrUnknownOrder is returned when the client ID was never journaled or its execution completed
+	ErrUnknownOrder = errors.New("Unknown order")
+	// ErrInvalidTransition is returned when the order cannot move to the new state
+	ErrInvalidTransition = errors.New("Invalid order state transition")
+)
+
+// Entry is a snapshot of an order written to the journal on every state change
+type Entry struct {
+	ClientID       string          `json:"client_id"`    // assigned by us before the order is sent
+	ExecutionID    string          `json:"execution_id"` // groups legs and hedges traded together
+	Exchange       string          `json:"exchange"`
+	OrderID        string          `json:"order_id,omitempty"` // assigned by the exchange on acknowledgement
+	Pair           types.Pair      `json:"pair"`
+	Side           types.Side      `json:"side"`
+	Price          decimal.Decimal `json:"price"`
+	Quantity       decimal.Decimal `json:"quantity"`
+	FilledQuantity decimal.Decimal `json:"filled_quantity"`
+	AveragePrice   decimal.Decimal `json:"average_price"`
+	Fee            decimal.Decimal `json:"fee"`
+	State          State           `json:"state"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"` // when the intent was journaled
+	Time           time.Time       `json:"time"`
+}
+
+// record is a single line of the journal file
+type record struct {
+	Order     *Entry `json:"order,omitempty"`
+	Completed string `json:"completed,omitempty"` // ID of an execution which needs no more attention
+}
+
+// Journal is an append-only file of order state changes, safe for concurrent use.
+// Every record is synced to disk before the call returns.
+type Journal struct {
+	file      *os.File
+	orders    map[string]*Entry // latest entry keyed by client ID, only of incomplete executions
+	byOrderID map[string]string // client ID keyed by exchange and order ID
+	mu        *sync.Mutex
+}
+
+// Open replays the journal file, compacts it to orders of incomplete executions
+// and opens it for appending, the file is created if it does not exist
+func Open(path string) (*Journal, error) {
+	j := &Journal{
+		orders:    make(map[string]*Entry),
+		byOrderID: make(map[string]string),
+		mu:        new(sync.Mutex),
+	}
+
+	if err := j.replay(path); err != nil {
+		return nil, err
+	}
+
+	if err := j.compact(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	j.file = file
+
+	return j, nil
+}
+
+// NewID returns a random identifier for client orders and executions
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Fall back to time which is unique enough for a single process
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Intent journals an order we are about to send, it must be called before the API call
+func (j *Journal) Intent(executionID, exchange string, pair types.Pair, side types.Side, price, quantity decimal.Decimal) (*Entry, error) {
+	now := time.Now()
+	entry := &Entry{
+		ClientID:       NewID(),
+		ExecutionID:    executionID,
+		Exchange:       exchange,
+		Pair:           pair,
+		Side:           side,
+		Price:          price,
+		Quantity:       quantity,
+		FilledQuantity: decimal.Zero,
+		AveragePrice:   decimal.Zero,
+		Fee:            decimal.Zero,
+		State:          StateNew,
+		CreatedAt:      now,
+		Time:           now,
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.write(&record{Order: entry}); err != nil {
+		return nil, err
+	}
+	j.apply(entry)
+
+	return entry, nil
+}
+
+// Acknowledge journals the order as returned by the exchange
+func (j *Journal) Acknowledge(clientID string, order *types.Order) error {
+	return j.transition(clientID, func(next *Entry) {
+		next.OrderID = order.ID
+		fill(next, order)
+	})
+}
+
+// Reject journals that the exchange refused the order
+func (j *Journal) Reject(clientID string, reason error) error {
+	return j.transition(clientID, func(next *Entry) {
+		next.State = StateRejected
+		next.Error = reason.Error()
+	})
+}
+
+// Update journals the latest state of an acknowledged order, unknown and unchanged orders are ignored
+func (j *Journal) Update(order *types.Order) error {
+	j.mu.Lock()
+	clientID, ok := j.byOrderID[orderKey(order.Exchange, order.ID)]
+	var changed bool
+	if ok {
+		latest := j.orders[clientID]
+		changed = latest.State != StateOf(order) || !latest.FilledQuantity.Equal(order.FilledQuantity)
+	}
+	j.mu.Unlock()
+
+	if !ok || !changed {
+		return nil
+	}
+
+	return j.transition(clientID, func(next *Entry) {
+		fill(next, order)
+	})
+}
+
+// Complete journals that the execution needs no more attention and forgets its orders
+func (j *Journal) Complete(executionID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.write(&record{Completed: executionID}); err != nil {
+		return err
+	}
+	j.forget(executionID)
+
+	return nil
+}
+
+// Incomplete returns latest entries of executions which were not completed keyed
+// by execution ID, entries of each execution are sorted by creation time
+func (j *Journal) Incomplete() map[string][]*Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	executions := make(map[string][]*Entry)
+	for _, entry := range j.orders {
+		copied := *entry
+		executions[entry.ExecutionID] = append(executions[entry.ExecutionID], &copied)
+	}
+
+	for _, entries := range executions {
+		sort.Slice(entries, func(a, b int) bool {
+			return entries[a].CreatedAt.Before(entries[b].CreatedAt)
+		})
+	}
+
+	return executions
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+// transition applies the change to a copy of the latest entry, checks the
+// state machine allows it and journals the result
+func (j *Journal) transition(clientID string, change func(next *Entry)) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	latest, ok := j.orders[clientID]
+	if !ok {
+		return fmt.Errorf("%v: %s", ErrUnknownOrder, clientID)
+	}
+
+	next := *latest
+	change(&next)
+	next.Time = time.Now()
+
+	if !latest.State.CanTransition(next.State) {
+		return fmt.Errorf("%v: %s from %s to %s", ErrInvalidTransition, clientID, latest.State, next.State)
+	}
+
+	if err := j.write(&record{Order: &next}); err != nil {
+		return err
+	}
+	j.apply(&next)
+
+	return nil
+}
+
+func (j *Journal) write(r *record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return j.file.Sync()
+}
+
+func (j *Journal) apply(entry *Entry) {
+	j.orders[entry.ClientID] = entry
+	if entry.OrderID != "" {
+		j.byOrderID[orderKey(entry.Exchange, entry.OrderID)] = entry.ClientID
+	}
+}
+
+func (j *Journal) forget(executionID string) {
+	for clientID, entry := range j.orders {
+		if entry.ExecutionID != executionID {
+			continue
+		}
+		delete(j.orders, clientID)
+		if entry.OrderID != "" {
+			delete(j.byOrderID, orderKey(entry.Exchange, entry.OrderID))
+		}
+	}
+}
+
+func (j *Journal) replay(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	var torn error
+	for scanner.Scan() {
+		// Only the last line can be incomplete, e.g. when we crashed while writing it
+		if torn != nil {
+			return torn
+		}
+
+		r := new(record)
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			torn = fmt.Errorf("Corrupted journal %s: %v", path, err)
+			continue
+		}
+
+		if r.Order != nil {
+			j.apply(r.Order)
+		}
+		if r.Completed != "" {
+			j.forget(r.Completed)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// compact rewrites the journal with latest entries of incomplete executions only
+func (j *Journal) compact(path string) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*Entry, 0, len(j.orders))
+	for _, entry := range j.orders {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Time.Before(entries[b].Time)
+	})
+
+	j.file = file
+	for _, entry := range entries {
+		if err := j.write(&record{Order: entry}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// fill copies execution details reported by the exchange
+func fill(entry *Entry, order *types.Order) {
+	entry.FilledQuantity = order.FilledQuantity
+	entry.AveragePrice = order.AveragePrice
+	entry.Fee = order.Fee
+	entry.State = StateOf(order)
+}
+
+func orderKey(exchange, orderID string) string {
+	return exchange + ":" + orderID
+}
@@ -0,0 +1,266 @@
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var pair = types.Pair{Base: "LTC", Quote: "BTC"}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name       string
+		tail       string // appended to the journal after it is closed
+		incomplete int    // executions left after reopening
+		wantErr    bool
+	}{
+		{
+			name:       "clean",
+			incomplete: 2,
+		},
+		{
+			name:       "torn last line",
+			tail:       `{"order":{"client_id":"torn","execution_id":"c","exch`,
+			incomplete: 2,
+		},
+		{
+			name:       "empty last line",
+			tail:       "\n",
+			incomplete: 2,
+		},
+		{
+			name:    "corrupted line followed by others",
+			tail:    "{\"order\":{\"client\n{\"completed\":\"a\"}\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := tempPath(t)
+			defer cleanup()
+
+			j, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, executionID := range []string{"a", "b", "done"} {
+				if _, err := j.Intent(executionID, "bittrex", pair, types.Buy, decimal.New(1, -2), decimal.New(1, 0)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := j.Complete("done"); err != nil {
+				t.Fatal(err)
+			}
+			j.Close()
+
+			if tt.tail != "" {
+				f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.WriteString(tt.tail)
+				f.Close()
+			}
+
+			j, err = Open(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("corrupted journal opened")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.Close()
+
+			incomplete := j.Incomplete()
+			if len(incomplete) != tt.incomplete {
+				t.Errorf("got %d incomplete executions, want %d", len(incomplete), tt.incomplete)
+			}
+			if _, ok := incomplete["done"]; ok {
+				t.Error("completed execution was replayed")
+			}
+
+			// Compaction drops the torn line so new records are not appended to it
+			if _, err := j.Intent("c", "bittrex", pair, types.Sell, decimal.New(1, -2), decimal.New(1, 0)); err != nil {
+				t.Fatal(err)
+			}
+			j.Close()
+			if j, err = Open(path); err != nil {
+				t.Fatalf("reopen error: %v", err)
+			}
+			defer j.Close()
+
+			if got := len(j.Incomplete()); got != tt.incomplete+1 {
+				t.Errorf("got %d incomplete executions after reopen, want %d", got, tt.incomplete+1)
+			}
+		})
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []types.OrderStatus // reported by the exchange after acknowledgement
+		filled  []string            // filled quantity of each update
+		state   State
+		wantErr bool
+	}{
+		{
+			name:    "filled",
+			updates: []types.OrderStatus{types.OrderPartiallyFilled, types.OrderFilled},
+			filled:  []string{"0.5", "1"},
+			state:   StateFilled,
+		},
+		{
+			name:    "cancelled after partial fill",
+			updates: []types.OrderStatus{types.OrderPartiallyFilled, types.OrderCancelled},
+			filled:  []string{"0.5", "0.5"},
+			state:   StateCancelled,
+		},
+		{
+			name:    "unchanged updates ignored",
+			updates: []types.OrderStatus{types.OrderOpen, types.OrderOpen},
+			filled:  []string{"0", "0"},
+			state:   StateAcknowledged,
+		},
+		{
+			name:    "filled order cannot be cancelled",
+			updates: []types.OrderStatus{types.OrderFilled, types.OrderCancelled},
+			filled:  []string{"1", "1"},
+			state:   StateFilled,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := tempPath(t)
+			defer cleanup()
+
+			j, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.Close()
+
+			entry, err := j.Intent("execution", "bittrex", pair, types.Buy, decimal.New(1, -2), decimal.New(1, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			order := &types.Order{ID: "order", Exchange: "bittrex", Status: types.OrderOpen, FilledQuantity: decimal.Zero}
+			if err := j.Acknowledge(entry.ClientID, order); err != nil {
+				t.Fatal(err)
+			}
+
+			var lastErr error
+			for i, status := range tt.updates {
+				filled, _ := decimal.NewFromString(tt.filled[i])
+				if err := j.Update(&types.Order{ID: "order", Exchange: "bittrex", Status: status, FilledQuantity: filled}); err != nil {
+					lastErr = err
+				}
+			}
+			if (lastErr != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", lastErr, tt.wantErr)
+			}
+			if lastErr != nil && !strings.HasPrefix(lastErr.Error(), ErrInvalidTransition.Error()) {
+				t.Errorf("error = %v, want %v", lastErr, ErrInvalidTransition)
+			}
+
+			if state := j.Incomplete()["execution"][0].State; state != tt.state {
+				t.Errorf("state = %s, want %s", state, tt.state)
+			}
+		})
+	}
+}
+
+func TestUnknownOrder(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	err = j.Acknowledge("unknown", &types.Order{ID: "order", Exchange: "bittrex"})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrUnknownOrder.Error()) {
+		t.Errorf("error = %v, want %v", err, ErrUnknownOrder)
+	}
+
+	// Updates of orders we did not send are ignored
+	if err := j.Update(&types.Order{ID: "order", Exchange: "bittrex", Status: types.OrderFilled}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from State
+		to   State
+		want bool
+	}{
+		{StateNew, StateAcknowledged, true},
+		{StateNew, StateFilled, true},
+		{StateNew, StateRejected, true},
+		{StateAcknowledged, StatePartiallyFilled, true},
+		{StateAcknowledged, StateRejected, false},
+		{StateAcknowledged, StateNew, false},
+		{StatePartiallyFilled, StatePartiallyFilled, true},
+		{StatePartiallyFilled, StateAcknowledged, false},
+		{StateFilled, StateCancelled, false},
+		{StateCancelled, StateFilled, false},
+		{StateRejected, StateAcknowledged, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s -> %s = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	for _, state := range []State{StateFilled, StateCancelled, StateRejected} {
+		if !state.IsTerminal() {
+			t.Errorf("%s is not terminal", state)
+		}
+	}
+}
+
+func TestStateOf(t *testing.T) {
+	tests := []struct {
+		status types.OrderStatus
+		filled decimal.Decimal
+		want   State
+	}{
+		{types.OrderOpen, decimal.Zero, StateAcknowledged},
+		{types.OrderOpen, decimal.New(1, -1), StatePartiallyFilled},
+		{types.OrderPartiallyFilled, decimal.New(1, -1), StatePartiallyFilled},
+		{types.OrderFilled, decimal.New(1, 0), StateFilled},
+		{types.OrderCancelled, decimal.Zero, StateCancelled},
+	}
+
+	for _, tt := range tests {
+		order := &types.Order{Status: tt.status, FilledQuantity: tt.filled}
+		if got := StateOf(order); got != tt.want {
+			t.Errorf("%s filled %s = %s, want %s", tt.status, tt.filled, got, tt.want)
+		}
+	}
+}
+
+func tempPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "orders.journal"), func() { os.RemoveAll(dir) }
+}
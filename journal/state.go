@@ -0,0 +1,63 @@
+package journal
+
+import (
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// State of an order in its lifecycle
+type State string
+
+const (
+	// StateNew means the intent was journaled, the exchange has not acknowledged the order yet
+	StateNew State = "new"
+	// StateAcknowledged means the exchange accepted the order, nothing is filled yet
+	StateAcknowledged State = "acknowledged"
+	// StatePartiallyFilled means some but not all quantity is filled
+	StatePartiallyFilled State = "partially_filled"
+	// StateFilled means all quantity is filled
+	StateFilled State = "filled"
+	// StateCancelled means the order was cancelled, possibly after a partial fill
+	StateCancelled State = "cancelled"
+	// StateRejected means the exchange refused the order or never received it
+	StateRejected State = "rejected"
+)
+
+// transitions lists states an order can move to from each state, terminal states have none.
+// A lost acknowledgement means a new order can show up in any state reported by the exchange.
+var transitions = map[State][]State{
+	StateNew:             {StateAcknowledged, StatePartiallyFilled, StateFilled, StateCancelled, StateRejected},
+	StateAcknowledged:    {StatePartiallyFilled, StateFilled, StateCancelled},
+	StatePartiallyFilled: {StatePartiallyFilled, StateFilled, StateCancelled},
+}
+
+// IsTerminal returns true for states an order never leaves
+func (s State) IsTerminal() bool {
+	return len(transitions[s]) == 0
+}
+
+// CanTransition returns true if an order can move from this state to the other one
+func (s State) CanTransition(to State) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StateOf maps order status reported by an exchange to a lifecycle state
+func StateOf(order *types.Order) State {
+	switch order.Status {
+	case types.OrderFilled:
+		return StateFilled
+	case types.OrderCancelled:
+		return StateCancelled
+	case types.OrderPartiallyFilled:
+		return StatePartiallyFilled
+	}
+
+	if order.FilledQuantity.Sign() > 0 {
+		return StatePartiallyFilled
+	}
+	return StateAcknowledged
+}
@@ -0,0 +1,293 @@
+// Package metrics exposes counters and histograms in the Prometheus text exposition format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// DefaultRegistry holds metrics created by NewCounter and NewHistogram
+	DefaultRegistry = NewRegistry()
+	// DefaultBuckets suit durations in seconds
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+// collector writes all series of a metric in the text exposition format
+type collector interface {
+	write(w io.Writer) error
+}
+
+// Registry exposes metrics in the Prometheus text format, safe for concurrent use
+type Registry struct {
+	collectors []collector
+	mu         *sync.Mutex
+}
+
+// NewRegistry returns new Registry instance
+func NewRegistry() *Registry {
+	return &Registry{mu: new(sync.Mutex)}
+}
+
+// Write writes all metrics in order of registration
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, c := range collectors {
+		if err := c.write(buffered); err != nil {
+			return err
+		}
+	}
+
+	return buffered.Flush()
+}
+
+// ServeHTTP serves metrics to Prometheus scrapes
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.Write(w)
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// Handler returns the HTTP handler of the default registry, usually mounted at /metrics
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+// Counter is a monotonically increasing value per combination of label values
+type Counter struct {
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+	mu     *sync.Mutex
+}
+
+// NewCounter returns new Counter registered in the default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+		mu:     new(sync.Mutex),
+	}
+	DefaultRegistry.register(c)
+
+	return c
+}
+
+// Inc adds one to the series of the label values, given in order of label names
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the value to the series of the label values, negative values are ignored
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+
+	key := formatLabels(c.labels, labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[key] += value
+}
+
+func (c *Counter) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, escapeHelp(c.help), c.name); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(c.values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.name, braces(key), formatValue(c.values[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Histogram counts observations in cumulative buckets per combination of label values
+type Histogram struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	series  map[string]*histogramSeries
+	mu      *sync.Mutex
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram returns new Histogram registered in the default registry, buckets
+// are upper bounds in increasing order, DefaultBuckets when nil
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+		mu:      new(sync.Mutex),
+	}
+	DefaultRegistry.register(h)
+
+	return h
+}
+
+// Observe adds the value to the series of the label values, given in order of label names
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := formatLabels(h.labels, labelValues)
+	i := sort.SearchFloat64s(h.buckets, value)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	// Values above the last bound are only counted by the implicit +Inf bucket
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, escapeHelp(h.help), h.name); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			le := joinLabels(key, `le="`+formatValue(bound)+`"`)
+			if _, err := fmt.Fprintf(w, "%s_bucket{%s} %d\n", h.name, le, cumulative); err != nil {
+				return err
+			}
+		}
+
+		le := joinLabels(key, `le="+Inf"`)
+		_, err := fmt.Fprintf(
+			w,
+			"%s_bucket{%s} %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, le, s.count,
+			h.name, braces(key), formatValue(s.sum),
+			h.name, braces(key), s.count,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formatLabels returns labels as they appear between braces, e.g. exchange="bittrex",status="filled"
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = name + `="` + escapeLabel(value) + `"`
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+
+	requests := NewCounter("test_requests_total", "Requests by path and \\ status.\nSecond line.", "path", "status")
+	requests.Inc("/public/getticker", "ok")
+	requests.Inc("/public/getticker", "ok")
+	requests.Add(2, `say "hi"`, "line\nbreak\\")
+	requests.Add(-1, "/public/getticker", "ok")
+	requests.Inc("/public/getmarkets")
+	r.register(requests)
+
+	quits := NewCounter("test_quits_total", "Quits.")
+	quits.Add(0.5)
+	r.register(quits)
+
+	durations := NewHistogram("test_duration_seconds", "Durations.", []float64{0.1, 1}, "path")
+	durations.Observe(0.05, "b")
+	durations.Observe(0.1, "b")
+	durations.Observe(5, "b")
+	durations.Observe(1, "a")
+	r.register(durations)
+
+	empty := NewHistogram("test_empty_seconds", "No observations.", nil)
+	r.register(empty)
+
+	want := `# HELP test_requests_total Requests by path and \\ status.\nSecond line.
+# TYPE test_requests_total counter
+test_requests_total{path="/public/getmarkets",status=""} 1
+test_requests_total{path="/public/getticker",status="ok"} 2
+test_requests_total{path="say \"hi\"",status="line\nbreak\\"} 2
+# HELP test_quits_total Quits.
+# TYPE test_quits_total counter
+test_quits_total 0.5
+# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{path="a",le="0.1"} 0
+test_duration_seconds_bucket{path="a",le="1"} 1
+test_duration_seconds_bucket{path="a",le="+Inf"} 1
+test_duration_seconds_sum{path="a"} 1
+test_duration_seconds_count{path="a"} 1
+test_duration_seconds_bucket{path="b",le="0.1"} 2
+test_duration_seconds_bucket{path="b",le="1"} 2
+test_duration_seconds_bucket{path="b",le="+Inf"} 3
+test_duration_seconds_sum{path="b"} 5.15
+test_duration_seconds_count{path="b"} 3
+# HELP test_empty_seconds No observations.
+# TYPE test_empty_seconds histogram
+`
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+
+	// Scrapes get the same text
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4" {
+		t.Errorf("content type = %s", contentType)
+	}
+	if rec.Body.String() != want {
+		t.Errorf("scraped =\n%s\nwant\n%s", rec.Body.String(), want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{0.005, "0.005"},
+		{2.5, "2.5"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tt := range tests {
+		if got := formatValue(tt.value); got != tt.want {
+			t.Errorf("%v = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package paper
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/shopspring/decimal"
+)
+
+// Config stores paper trading options
+type Config struct {
+	Balances    map[string]decimal.Decimal // initial virtual balances keyed by currency
+	Fee         decimal.Decimal            // commission rate charged on value of each fill
+	SlippageBps decimal.Decimal            // fills happen this much worse than the observed quote
+}
+
+// ReadBalances decodes initial balances of several exchanges from JSON, e.g. {"bittrex": {"BTC": "1.5"}}
+func ReadBalances(r io.Reader) (map[string]map[string]decimal.Decimal, error) {
+	balances := make(map[string]map[string]decimal.Decimal)
+	if err := json.NewDecoder(r).Decode(&balances); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
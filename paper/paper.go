@@ -0,0 +1,310 @@
+// Package paper wraps an exchange to trade against its live market data without risking funds
+package paper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrInsufficientBalance is returned when virtual balance cannot cover the order
+	ErrInsufficientBalance = errors.New("Insufficient balance")
+	// ErrOrderNotFound is returned for unknown order IDs
+	ErrOrderNotFound = errors.New("Order not found")
+	// ErrOrderClosed is returned when cancelling an order which is already closed
+	ErrOrderClosed = errors.New("Order already closed")
+	// ErrInvalidOrder is returned for orders with non positive price or quantity
+	ErrInvalidOrder = errors.New("Price and quantity must be positive")
+
+	one        = decimal.New(1, 0)
+	bpsPerUnit = decimal.New(10000, 0)
+)
+
+// Exchange passes through tickers of the wrapped exchange and simulates trading
+// and balances by filling orders against the latest observed bid and ask
+type Exchange struct {
+	exchange  types.Exchange
+	cnf       *Config
+	tickers   map[types.Pair]*types.Ticker
+	available map[string]decimal.Decimal
+	total     map[string]decimal.Decimal
+	orders    map[string]*types.Order
+	reserved  map[string]decimal.Decimal // funds locked by open orders keyed by order ID
+	nextID    int64
+	mu        *sync.Mutex
+	quit      chan int
+	quitOnce  *sync.Once
+}
+
+// New returns new instance of Exchange wrapping the exchange
+func New(exchange types.Exchange, cnf *Config) *Exchange {
+	e := &Exchange{
+		exchange:  exchange,
+		cnf:       cnf,
+		tickers:   make(map[types.Pair]*types.Ticker),
+		available: make(map[string]decimal.Decimal),
+		total:     make(map[string]decimal.Decimal),
+		orders:    make(map[string]*types.Order),
+		reserved:  make(map[string]decimal.Decimal),
+		mu:        new(sync.Mutex),
+		quit:      make(chan int),
+		quitOnce:  new(sync.Once),
+	}
+
+	for currency, amount := range cnf.Balances {
+		e.available[currency] = amount
+		e.total[currency] = amount
+	}
+
+	return e
+}
+
+// GetName returns name of the wrapped exchange so quotes are matched across venues as usual
+func (e *Exchange) GetName() string {
+	return e.exchange.GetName()
+}
+
+// Run ...
+func (e *Exchange) Run(tickers chan *types.Ticker) error {
+	upstream := make(chan *types.Ticker)
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- e.exchange.Run(upstream)
+	}()
+
+	// Keep draining upstream tickers until we quit so the wrapped exchange never blocks
+	for {
+		select {
+		case ticker := <-upstream:
+			e.update(ticker)
+			tickers <- ticker
+		case err := <-errChan:
+			if err != nil {
+				return err
+			}
+		case <-e.quit:
+			return nil
+		}
+	}
+}
+
+// RunOrderBooks passes through order books if the wrapped exchange streams them
+func (e *Exchange) RunOrderBooks(books chan *types.OrderBook) error {
+	if streamer, ok := e.exchange.(types.OrderBookStreamer); ok {
+		return streamer.RunOrderBooks(books)
+	}
+
+	return nil
+}
+
+// Quit ...
+func (e *Exchange) Quit() error {
+	err := e.exchange.Quit()
+
+	e.quitOnce.Do(func() {
+		close(e.quit)
+	})
+
+	return err
+}
+
+// FetchBalances returns virtual balances
+func (e *Exchange) FetchBalances() ([]*types.Balance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	balances := make([]*types.Balance, 0, len(e.total))
+	for currency, total := range e.total {
+		balances = append(balances, &types.Balance{
+			Currency:  currency,
+			Available: e.available[currency],
+			Pending:   decimal.Zero,
+			Total:     total,
+		})
+	}
+
+	return balances, nil
+}
+
+// PlaceOrder reserves virtual funds and fills the order straight away if the latest quote allows it
+func (e *Exchange) PlaceOrder(pair types.Pair, side types.Side, price, quantity decimal.Decimal) (*types.Order, error) {
+	if price.Sign() <= 0 || quantity.Sign() <= 0 {
+		return nil, ErrInvalidOrder
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Buy orders lock quote currency including the fee, sell orders lock base currency
+	currency, reserve := pair.Base, quantity
+	if side == types.Buy {
+		currency, reserve = pair.Quote, price.Mul(quantity).Mul(one.Add(e.cnf.Fee))
+	}
+
+	if e.available[currency].LessThan(reserve) {
+		return nil, fmt.Errorf("%v: %s", ErrInsufficientBalance, currency)
+	}
+
+	e.nextID++
+	now := time.Now()
+	order := &types.Order{
+		ID:             fmt.Sprintf("paper-%s-%d", e.GetName(), e.nextID),
+		Exchange:       e.GetName(),
+		Pair:           pair,
+		Side:           side,
+		Price:          price,
+		Quantity:       quantity,
+		FilledQuantity: decimal.Zero,
+		AveragePrice:   decimal.Zero,
+		Fee:            decimal.Zero,
+		Status:         types.OrderOpen,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	e.available[currency] = e.available[currency].Sub(reserve)
+	e.reserved[order.ID] = reserve
+	e.orders[order.ID] = order
+
+	if ticker, ok := e.tickers[pair]; ok {
+		e.match(order, ticker)
+	}
+
+	return copyOrder(order), nil
+}
+
+// CancelOrder releases funds reserved by the order
+func (e *Exchange) CancelOrder(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	order, ok := e.orders[id]
+	if !ok {
+		return ErrOrderNotFound
+	}
+
+	if order.IsClosed() {
+		return ErrOrderClosed
+	}
+
+	e.release(order)
+	order.Status = types.OrderCancelled
+	order.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// FetchOrder ...
+func (e *Exchange) FetchOrder(id string) (*types.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	order, ok := e.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+
+	return copyOrder(order), nil
+}
+
+// FetchOpenOrders ...
+func (e *Exchange) FetchOpenOrders(pair types.Pair) ([]*types.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var orders []*types.Order
+	for _, order := range e.orders {
+		if order.IsClosed() || (pair != (types.Pair{}) && order.Pair != pair) {
+			continue
+		}
+		orders = append(orders, copyOrder(order))
+	}
+
+	return orders, nil
+}
+
+// update remembers the ticker and fills open orders it crosses
+func (e *Exchange) update(ticker *types.Ticker) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.tickers[ticker.Pair] = ticker
+
+	for _, order := range e.orders {
+		if !order.IsClosed() && order.Pair == ticker.Pair {
+			e.match(order, ticker)
+		}
+	}
+}
+
+// match fills the whole order if the quote crosses its limit price, top of book
+// carries no size so we assume the quote is deep enough
+func (e *Exchange) match(order *types.Order, ticker *types.Ticker) {
+	slippage := e.cnf.SlippageBps.Div(bpsPerUnit)
+
+	var price decimal.Decimal
+	if order.Side == types.Buy {
+		if ticker.Ask.Sign() <= 0 || ticker.Ask.GreaterThan(order.Price) {
+			return
+		}
+		price = decimal.Min(order.Price, ticker.Ask.Mul(one.Add(slippage)))
+	} else {
+		if ticker.Bid.Sign() <= 0 || ticker.Bid.LessThan(order.Price) {
+			return
+		}
+		price = decimal.Max(order.Price, ticker.Bid.Mul(one.Sub(slippage)))
+	}
+
+	value := price.Mul(order.Quantity)
+	fee := value.Mul(e.cnf.Fee)
+	base, quote := order.Pair.Base, order.Pair.Quote
+
+	if order.Side == types.Buy {
+		// Return whatever part of the reservation was not spent
+		e.available[quote] = e.available[quote].Add(e.reserved[order.ID]).Sub(value).Sub(fee)
+		e.total[quote] = e.total[quote].Sub(value).Sub(fee)
+		e.available[base] = e.available[base].Add(order.Quantity)
+		e.total[base] = e.total[base].Add(order.Quantity)
+	} else {
+		e.total[base] = e.total[base].Sub(order.Quantity)
+		e.available[quote] = e.available[quote].Add(value).Sub(fee)
+		e.total[quote] = e.total[quote].Add(value).Sub(fee)
+	}
+	delete(e.reserved, order.ID)
+
+	order.FilledQuantity = order.Quantity
+	order.AveragePrice = price
+	order.Fee = fee
+	order.Status = types.OrderFilled
+	order.UpdatedAt = time.Now()
+}
+
+func (e *Exchange) release(order *types.Order) {
+	currency := order.Pair.Base
+	if order.Side == types.Buy {
+		currency = order.Pair.Quote
+	}
+
+	e.available[currency] = e.available[currency].Add(e.reserved[order.ID])
+	delete(e.reserved, order.ID)
+}
+
+func copyOrder(order *types.Order) *types.Order {
+	c := *order
+	return &c
+}
+
+// FetchTradingRules passes through trading rules if the wrapped exchange describes them
+func (e *Exchange) FetchTradingRules() ([]*types.TradingRules, error) {
+	if provider, ok := e.exchange.(types.RulesProvider); ok {
+		return provider.FetchTradingRules()
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,274 @@
+package paper
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+var ltcBtc = types.Pair{Base: "LTC", Quote: "BTC"}
+
+// fakeExchange only provides a name, tests feed tickers to paper exchanges directly
+type fakeExchange struct{}
+
+func (e *fakeExchange) GetName() string                      { return "fake" }
+func (e *fakeExchange) Run(tickers chan *types.Ticker) error { return nil }
+func (e *fakeExchange) Quit() error                          { return nil }
+
+// newExchange returns a paper exchange holding 1 BTC and 10 LTC quoted at 0.0099/0.01
+func newExchange(fee, slippageBps string) *Exchange {
+	e := New(new(fakeExchange), &Config{
+		Balances:    map[string]decimal.Decimal{"BTC": mustDecimal("1"), "LTC": mustDecimal("10")},
+		Fee:         mustDecimal(fee),
+		SlippageBps: mustDecimal(slippageBps),
+	})
+	e.update(&types.Ticker{Exchange: "fake", Pair: ltcBtc, Bid: mustDecimal("0.0099"), Ask: mustDecimal("0.01")})
+	return e
+}
+
+func TestPlaceOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		fee         string
+		slippageBps string
+		side        types.Side
+		price       string
+		quantity    string
+		status      types.OrderStatus
+		average     string
+		orderFee    string
+		balances    string // currency available/total
+		err         string
+	}{
+		{
+			name:        "buy crossing the ask",
+			fee:         "0.0025",
+			slippageBps: "0",
+			side:        types.Buy,
+			price:       "0.011",
+			quantity:    "10",
+			status:      types.OrderFilled,
+			average:     "0.01",
+			orderFee:    "0.00025",
+			balances:    "BTC 0.89975/0.89975, LTC 20/20",
+		},
+		{
+			name:        "buy with slippage",
+			fee:         "0",
+			slippageBps: "50",
+			side:        types.Buy,
+			price:       "0.011",
+			quantity:    "10",
+			status:      types.OrderFilled,
+			average:     "0.01005",
+			orderFee:    "0",
+			balances:    "BTC 0.8995/0.8995, LTC 20/20",
+		},
+		{
+			name:        "buy slippage capped at the limit",
+			fee:         "0",
+			slippageBps: "50",
+			side:        types.Buy,
+			price:       "0.01002",
+			quantity:    "10",
+			status:      types.OrderFilled,
+			average:     "0.01002",
+			orderFee:    "0",
+			balances:    "BTC 0.8998/0.8998, LTC 20/20",
+		},
+		{
+			name:        "sell crossing the bid",
+			fee:         "0.0025",
+			slippageBps: "100",
+			side:        types.Sell,
+			price:       "0.009",
+			quantity:    "4",
+			status:      types.OrderFilled,
+			average:     "0.009801",
+			orderFee:    "0.00009801",
+			balances:    "BTC 1.03910599/1.03910599, LTC 6/6",
+		},
+		{
+			name:        "sell slippage capped at the limit",
+			fee:         "0",
+			slippageBps: "100",
+			side:        types.Sell,
+			price:       "0.0099",
+			quantity:    "4",
+			status:      types.OrderFilled,
+			average:     "0.0099",
+			orderFee:    "0",
+			balances:    "BTC 1.0396/1.0396, LTC 6/6",
+		},
+		{
+			// Quote currency including the fee stays reserved until the order fills
+			name:        "buy below the ask",
+			fee:         "0.0025",
+			slippageBps: "0",
+			side:        types.Buy,
+			price:       "0.009",
+			quantity:    "10",
+			status:      types.OrderOpen,
+			average:     "0",
+			orderFee:    "0",
+			balances:    "BTC 0.909775/1, LTC 10/10",
+		},
+		{
+			name:        "sell above the bid",
+			fee:         "0.0025",
+			slippageBps: "0",
+			side:        types.Sell,
+			price:       "0.011",
+			quantity:    "4",
+			status:      types.OrderOpen,
+			average:     "0",
+			orderFee:    "0",
+			balances:    "BTC 1/1, LTC 6/10",
+		},
+		{
+			name:        "insufficient balance",
+			fee:         "0.0025",
+			slippageBps: "0",
+			side:        types.Buy,
+			price:       "0.01",
+			quantity:    "100",
+			balances:    "BTC 1/1, LTC 10/10",
+			err:         "Insufficient balance: BTC",
+		},
+		{
+			name:        "zero quantity",
+			fee:         "0",
+			slippageBps: "0",
+			side:        types.Sell,
+			price:       "0.01",
+			quantity:    "0",
+			balances:    "BTC 1/1, LTC 10/10",
+			err:         ErrInvalidOrder.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newExchange(tt.fee, tt.slippageBps)
+
+			order, err := e.PlaceOrder(ltcBtc, tt.side, mustDecimal(tt.price), mustDecimal(tt.quantity))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("error = %v, want %s", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else {
+				if order.Status != tt.status {
+					t.Errorf("status = %s, want %s", order.Status, tt.status)
+				}
+				assertDecimal(t, "average price", order.AveragePrice, tt.average)
+				assertDecimal(t, "fee", order.Fee, tt.orderFee)
+			}
+
+			if got := balances(t, e); got != tt.balances {
+				t.Errorf("balances = %s, want %s", got, tt.balances)
+			}
+		})
+	}
+}
+
+func TestRestingOrder(t *testing.T) {
+	e := newExchange("0.0025", "0")
+
+	resting, err := e.PlaceOrder(ltcBtc, types.Buy, mustDecimal("0.009"), mustDecimal("10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := e.PlaceOrder(ltcBtc, types.Sell, mustDecimal("0.02"), mustDecimal("4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open, _ := e.FetchOpenOrders(ltcBtc); len(open) != 2 {
+		t.Errorf("got %d open orders, want 2", len(open))
+	}
+	if open, _ := e.FetchOpenOrders(types.Pair{Base: "ETH", Quote: "BTC"}); len(open) != 0 {
+		t.Errorf("got %d open ETH/BTC orders, want none", len(open))
+	}
+
+	// Cancelling releases the reservation
+	if err := e.CancelOrder(cancelled.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := balances(t, e), "BTC 0.909775/1, LTC 10/10"; got != want {
+		t.Errorf("balances after cancel = %s, want %s", got, want)
+	}
+
+	// The resting order fills once the ask comes down to its price
+	e.update(&types.Ticker{Exchange: "fake", Pair: ltcBtc, Bid: mustDecimal("0.0085"), Ask: mustDecimal("0.0089")})
+	order, err := e.FetchOrder(resting.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != types.OrderFilled {
+		t.Fatalf("status = %s, want filled", order.Status)
+	}
+	assertDecimal(t, "average price", order.AveragePrice, "0.0089")
+	assertDecimal(t, "filled", order.FilledQuantity, "10")
+
+	// Unspent reservation is returned, the fee is charged on the fill price
+	if got, want := balances(t, e), "BTC 0.9107775/0.9107775, LTC 20/20"; got != want {
+		t.Errorf("balances after fill = %s, want %s", got, want)
+	}
+	if open, _ := e.FetchOpenOrders(types.Pair{}); len(open) != 0 {
+		t.Errorf("got %d open orders, want none", len(open))
+	}
+
+	// Orders handed out are copies
+	resting.Status = types.OrderCancelled
+	if order, _ := e.FetchOrder(resting.ID); order.Status != types.OrderFilled {
+		t.Errorf("status = %s after changing a copy, want filled", order.Status)
+	}
+
+	if err := e.CancelOrder(resting.ID); err != ErrOrderClosed {
+		t.Errorf("cancel filled order error = %v, want %v", err, ErrOrderClosed)
+	}
+	if err := e.CancelOrder("unknown"); err != ErrOrderNotFound {
+		t.Errorf("cancel unknown order error = %v, want %v", err, ErrOrderNotFound)
+	}
+	if _, err := e.FetchOrder("unknown"); err != ErrOrderNotFound {
+		t.Errorf("fetch unknown order error = %v, want %v", err, ErrOrderNotFound)
+	}
+}
+
+// balances formats available and total virtual balances of every currency
+func balances(t *testing.T, e *Exchange) string {
+	t.Helper()
+
+	fetched, err := e.FetchBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted := make([]string, len(fetched))
+	for i, b := range fetched {
+		formatted[i] = b.Currency + " " + b.Available.String() + "/" + b.Total.String()
+	}
+	sort.Strings(formatted)
+
+	return strings.Join(formatted, ", ")
+}
+
+func mustDecimal(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+
+	if !got.Equal(mustDecimal(want)) {
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
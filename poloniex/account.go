@@ -0,0 +1,25 @@
+package poloniex
+
+import (
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// FetchCurrencies returns transfer properties of all currencies using canonical currency symbols
+func (e *Exchange) FetchCurrencies() ([]*types.Currency, error) {
+	currencies, err := e.ReturnCurrencies()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*types.Currency, 0, len(currencies))
+	for symbol, c := range currencies {
+		result = append(result, &types.Currency{
+			Currency:         e.cnf.Symbols.Currency(e.GetName(), symbol),
+			WithdrawalFee:    c.TxFee,
+			MinConfirmations: c.MinConf,
+			Active:           c.Disabled == 0 && c.Delisted == 0 && c.Frozen == 0,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+)
+
+const (
+	// APIHost is the domain name used for API endpoints
+	APIHost = "https://poloniex.com"
+	// ReturnTickerEndpoint is a public endpoint to get tickers for all markets
+	ReturnTickerEndpoint = "/public?command=returnTicker"
+	// ReturnCurrenciesEndpoint is a public endpoint to get traded currencies
+	ReturnCurrenciesEndpoint = "/public?command=returnCurrencies"
+)
+
+// ReturnTicker returns tickers for all markets keyed by market name
+func (e *Exchange) ReturnTicker() (map[string]*Ticker, error) {
+	data, err := e.makeGetRequest(ReturnTickerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make(map[string]*Ticker)
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ReturnCurrencies returns all traded currencies keyed by currency symbol
+func (e *Exchange) ReturnCurrencies() (map[string]*Currency, error) {
+	data, err := e.makeGetRequest(ReturnCurrenciesEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make(map[string]*Currency)
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (e *Exchange) makeGetRequest(path string) ([]byte, error) {
+	resp, err := e.client.Get(e.cnf.Host + path)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// Failed requests return an object with error message instead of the result
+	errResponse := new(ErrorResponse)
+	if err := json.Unmarshal(data, errResponse); err == nil && errResponse.Error != "" {
+		return []byte{}, errors.New(errResponse.Error)
+	}
+
+	return data, nil
+}
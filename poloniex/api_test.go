@@ -0,0 +1,171 @@
+package poloniex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// newServer responds to every command with the body registered for it
+func newServer(t *testing.T, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		command := r.URL.Query().Get("command")
+		response, ok := responses[command]
+		if r.URL.Path != "/public" || !ok {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, response)
+	}))
+}
+
+func TestReturnTicker(t *testing.T) {
+	server := newServer(t, map[string]string{
+		"returnTicker": `{
+			"BTC_LTC": {"id":50,"last":"0.0101","lowestAsk":"0.0102","highestBid":"0.01","percentChange":"-0.01","baseVolume":"123.4","quoteVolume":"12345.6","isFrozen":"0","high24hr":"0.0105","low24hr":"0.0099"},
+			"BTC_XMR": {"id":114,"last":"0.02","lowestAsk":"0.021","highestBid":"0.019","percentChange":"0","baseVolume":"1","quoteVolume":"50","isFrozen":"1","high24hr":"0.02","low24hr":"0.02"}
+		}`,
+	})
+	defer server.Close()
+
+	e := New(&Config{Host: server.URL})
+	response, err := e.ReturnTicker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response) != 2 {
+		t.Fatalf("got %d tickers, want 2", len(response))
+	}
+
+	ticker := response["BTC_LTC"]
+	if ticker == nil {
+		t.Fatal("BTC_LTC ticker missing")
+	}
+	if ticker.ID != 50 || ticker.IsFrozen != "0" {
+		t.Errorf("ticker = %+v", ticker)
+	}
+	assertDecimal(t, "last", ticker.Last, "0.0101")
+	assertDecimal(t, "lowest ask", ticker.LowestAsk, "0.0102")
+	assertDecimal(t, "highest bid", ticker.HighestBid, "0.01")
+	assertDecimal(t, "percent change", ticker.PercentChange, "-0.01")
+	assertDecimal(t, "base volume", ticker.BaseVolume, "123.4")
+	assertDecimal(t, "quote volume", ticker.QuoteVolume, "12345.6")
+	assertDecimal(t, "high", ticker.High24hr, "0.0105")
+	assertDecimal(t, "low", ticker.Low24hr, "0.0099")
+
+	// Frozen markets are not emitted
+	tickers := make(chan *types.Ticker, len(response))
+	if err := e.getTickers(tickers); err != nil {
+		t.Fatal(err)
+	}
+	close(tickers)
+	var emitted []*types.Ticker
+	for ticker := range tickers {
+		emitted = append(emitted, ticker)
+	}
+	if len(emitted) != 1 {
+		t.Fatalf("emitted %d tickers, want 1", len(emitted))
+	}
+	if emitted[0].Market != "BTC_LTC" || emitted[0].Pair != (types.Pair{Base: "LTC", Quote: "BTC"}) {
+		t.Errorf("emitted %s %s, want BTC_LTC LTC/BTC", emitted[0].Market, emitted[0].Pair)
+	}
+	assertDecimal(t, "bid", emitted[0].Bid, "0.01")
+	assertDecimal(t, "ask", emitted[0].Ask, "0.0102")
+}
+
+func TestReturnCurrencies(t *testing.T) {
+	server := newServer(t, map[string]string{
+		"returnCurrencies": `{
+			"BTC": {"id":28,"name":"Bitcoin","txFee":"0.0005","minConf":1,"depositAddress":null,"disabled":0,"delisted":0,"frozen":0},
+			"XMR": {"id":256,"name":"Monero","txFee":"0.01","minConf":6,"depositAddress":"4JUdGz","disabled":0,"delisted":0,"frozen":1}
+		}`,
+	})
+	defer server.Close()
+
+	e := New(&Config{Host: server.URL})
+	response, err := e.ReturnCurrencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btc, xmr := response["BTC"], response["XMR"]
+	if btc == nil || xmr == nil {
+		t.Fatalf("currencies = %v, want BTC and XMR", response)
+	}
+	if btc.ID != 28 || btc.Name != "Bitcoin" || btc.MinConf != 1 || btc.DepositAddress != nil {
+		t.Errorf("BTC = %+v", btc)
+	}
+	assertDecimal(t, "BTC fee", btc.TxFee, "0.0005")
+	if xmr.DepositAddress == nil || *xmr.DepositAddress != "4JUdGz" || xmr.Frozen != 1 {
+		t.Errorf("XMR = %+v", xmr)
+	}
+
+	// Frozen currencies cannot be transferred
+	currencies, err := e.FetchCurrencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range currencies {
+		if c.Active != (c.Currency == "BTC") {
+			t.Errorf("%s active = %v", c.Currency, c.Active)
+		}
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	server := newServer(t, map[string]string{
+		"returnTicker":     `{"error":"Invalid command."}`,
+		"returnCurrencies": `[]`,
+	})
+	defer server.Close()
+
+	e := New(&Config{Host: server.URL})
+	if _, err := e.ReturnTicker(); err == nil || err.Error() != "Invalid command." {
+		t.Errorf("error = %v, want Invalid command.", err)
+	}
+
+	// Responses which are not an error object are decoded as a result
+	if _, err := e.ReturnCurrencies(); err == nil {
+		t.Error("array decoded as currencies")
+	}
+}
+
+func TestQuit(t *testing.T) {
+	server := newServer(t, map[string]string{"returnTicker": `{}`})
+	defer server.Close()
+
+	e := New(&Config{Host: server.URL, PollInterval: time.Hour})
+	done := make(chan error)
+	go func() {
+		done <- e.Run(make(chan *types.Ticker))
+	}()
+
+	// Quitting twice does not block
+	e.Quit()
+	e.Quit()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ticker loop did not quit")
+	}
+}
+
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+
+	w, err := decimal.NewFromString(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(w) {
+		t.Errorf("%s = %s, want %s", name, got, w)
+	}
+}
@@ -0,0 +1,27 @@
+package poloniex
+
+import (
+	"time"
+
+	"github.com/RichardKnop/arbitrage/symbols"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// DefaultPollInterval ...
+	DefaultPollInterval = 2 * time.Second
+)
+
+var (
+	// TradingFee is the taker commission rate charged on every trade
+	TradingFee = decimal.New(25, -4)
+	// MakerFee is the commission rate charged on trades providing liquidity
+	MakerFee = decimal.New(15, -4)
+)
+
+// Config stores Poloniex configuration options
+type Config struct {
+	Host         string
+	PollInterval time.Duration     // how long to wait between two ticker requests, each returns all markets
+	Symbols      *symbols.Registry // maps market names to canonical pairs, default registry when nil
+}
@@ -1,2 +1,142 @@
 // Package poloniex wraps the exchange API, see: https://poloniex.com/public?command=returnTicker
 package poloniex
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/symbols"
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+const (
+	// Name is a unique exchange name
+	Name = "poloniex"
+)
+
+var (
+	// ErrInvalidMarketName is returned when market name is not in QUOTE_BASE format
+	//
+	// Deprecated: symbols.ErrInvalidMarketName is returned by the registry.
+	ErrInvalidMarketName = errors.New("Invalid market name")
+)
+
+// Exchange wraps methods that interact with exchange
+type Exchange struct {
+	cnf      *Config
+	client   *http.Client
+	quit     chan int
+	quitOnce *sync.Once
+}
+
+// New returns new instance of Exchange
+func New(cnf *Config) *Exchange {
+	secs := time.Duration(3) // set timeouts to reasonably low period
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			Dial: (&net.Dialer{
+				Timeout:   secs * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).Dial,
+			TLSHandshakeTimeout: secs * time.Second,
+		},
+	}
+
+	if cnf.Symbols == nil {
+		cnf.Symbols = symbols.New(nil)
+	}
+
+	return &Exchange{
+		cnf:      cnf,
+		client:   client,
+		quit:     make(chan int),
+		quitOnce: new(sync.Once),
+	}
+}
+
+// ParseMarketName splits a market name such as BTC_LTC into LTC/BTC pair
+//
+// Deprecated: use symbols.Registry.ParsePair, which also applies currency aliases.
+func ParseMarketName(marketName string) (types.Pair, error) {
+	parts := strings.Split(marketName, "_")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.Pair{}, ErrInvalidMarketName
+	}
+
+	return types.Pair{Base: parts[1], Quote: parts[0]}, nil
+}
+
+// GetName returns a unique identifier for this exchange
+func (e *Exchange) GetName() string {
+	return Name
+}
+
+// Run ...
+func (e *Exchange) Run(tickers chan *types.Ticker) error {
+	for {
+		if err := e.getTickers(tickers); err != nil {
+			log.Print(err)
+		}
+
+		// Capture quit channel here so we can exit the loop
+		select {
+		case <-e.quit:
+			return nil
+		case <-time.After(e.cnf.PollInterval):
+		}
+	}
+}
+
+// Quit ...
+func (e *Exchange) Quit() error {
+	// Closing the channel does not block when the loop is not running
+	log.Printf("[%s] Quitting the ticker loop", e.GetName())
+	e.quitOnce.Do(func() {
+		close(e.quit)
+	})
+
+	return nil
+}
+
+func (e *Exchange) getTickers(tickers chan *types.Ticker) error {
+	// A single request returns tickers for all markets
+	response, err := e.ReturnTicker()
+	if err != nil {
+		return fmt.Errorf("[%s] Return ticker error: %v", e.GetName(), err)
+	}
+
+	now := time.Now()
+	for marketName, ticker := range response {
+		// Frozen markets cannot be traded
+		if ticker.IsFrozen == "1" {
+			continue
+		}
+
+		pair, err := e.cnf.Symbols.ParsePair(e.GetName(), marketName)
+		if err != nil {
+			log.Printf("[%s] Parse market name '%s' error: %v", e.GetName(), marketName, err)
+			continue
+		}
+
+		// Tickers carry no time stamp, receive time is the best we know
+		tickers <- &types.Ticker{
+			Exchange:   e.GetName(),
+			Market:     marketName,
+			Pair:       pair,
+			Bid:        ticker.HighestBid,
+			Ask:        ticker.LowestAsk,
+			Last:       ticker.Last,
+			Time:       now,
+			ReceivedAt: now,
+		}
+	}
+
+	return nil
+}
@@ -1 +1,36 @@
 package poloniex
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// ErrorResponse is returned by the API instead of the expected result when request fails
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Ticker ...
+type Ticker struct {
+	ID            int             `json:"id"`
+	Last          decimal.Decimal `json:"last"`
+	LowestAsk     decimal.Decimal `json:"lowestAsk"`
+	HighestBid    decimal.Decimal `json:"highestBid"`
+	PercentChange decimal.Decimal `json:"percentChange"`
+	BaseVolume    decimal.Decimal `json:"baseVolume"`
+	QuoteVolume   decimal.Decimal `json:"quoteVolume"`
+	IsFrozen      string          `json:"isFrozen"`
+	High24hr      decimal.Decimal `json:"high24hr"`
+	Low24hr       decimal.Decimal `json:"low24hr"`
+}
+
+// Currency ...
+type Currency struct {
+	ID             int             `json:"id"`
+	Name           string          `json:"name"`
+	TxFee          decimal.Decimal `json:"txFee"`
+	MinConf        int             `json:"minConf"`
+	DepositAddress *string         `json:"depositAddress"`
+	Disabled       int             `json:"disabled"`
+	Delisted       int             `json:"delisted"`
+	Frozen         int             `json:"frozen"`
+}
@@ -0,0 +1,36 @@
+package recorder
+
+import (
+	"time"
+)
+
+// Format of recorded files
+type Format string
+
+const (
+	// JSONLines writes one JSON encoded Record per line
+	JSONLines Format = "jsonl"
+	// CSV writes one row per record, order book levels are packed into bids and asks columns
+	CSV Format = "csv"
+)
+
+const (
+	// DefaultPrefix ...
+	DefaultPrefix = "ticks"
+	// DefaultMaxSize ...
+	DefaultMaxSize = 100 * 1024 * 1024
+	// DefaultRotateInterval ...
+	DefaultRotateInterval = time.Hour
+	// DefaultFlushInterval ...
+	DefaultFlushInterval = time.Second
+)
+
+// Config stores recorder options
+type Config struct {
+	Dir            string        // directory files are written to, created when missing
+	Prefix         string        // file names start with the prefix followed by the time the file was opened
+	Format         Format        // JSONLines unless set
+	MaxSize        int64         // compressed bytes after which a new file is started, zero means no limit
+	RotateInterval time.Duration // age after which a new file is started, zero means no limit
+	FlushInterval  time.Duration // how often buffered records are flushed, zero means after every record
+}
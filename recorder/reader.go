@@ -0,0 +1,242 @@
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// Reader decodes records from a file written by Recorder
+type Reader struct {
+	file *os.File
+	gz   *gzip.Reader
+	csv  *csv.Reader
+	json *json.Decoder
+}
+
+// Open returns a Reader of the recorded file, the format is told by the file extension
+func Open(path string) (*Reader, error) {
+	var format Format
+	switch {
+	case strings.HasSuffix(path, "."+string(JSONLines)+".gz"):
+		format = JSONLines
+	case strings.HasSuffix(path, "."+string(CSV)+".gz"):
+		format = CSV
+	default:
+		return nil, fmt.Errorf("%v: %s", ErrUnknownFormat, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	r := &Reader{file: file, gz: gz}
+	if format == JSONLines {
+		r.json = json.NewDecoder(gz)
+		return r, nil
+	}
+
+	r.csv = csv.NewReader(gz)
+	r.csv.FieldsPerRecord = len(csvHeader)
+	if _, err := r.csv.Read(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Next returns the next record, io.EOF once all records were read
+func (r *Reader) Next() (*Record, error) {
+	if r.json != nil {
+		record := new(Record)
+		if err := r.json.Decode(record); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	row, err := r.csv.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRow(row)
+}
+
+// Close closes the file
+func (r *Reader) Close() error {
+	r.gz.Close()
+	return r.file.Close()
+}
+
+func parseRow(row []string) (*Record, error) {
+	exchangeTime, err := time.Parse(timeLayout, row[5])
+	if err != nil {
+		return nil, err
+	}
+
+	receivedAt, err := time.Parse(timeLayout, row[6])
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{Type: RecordType(row[0]), ReceivedAt: receivedAt}
+	pair := types.Pair{Base: row[3], Quote: row[4]}
+
+	if record.Type == OrderBookRecord {
+		bids, err := parseLevels(row[10])
+		if err != nil {
+			return nil, err
+		}
+
+		asks, err := parseLevels(row[11])
+		if err != nil {
+			return nil, err
+		}
+
+		record.OrderBook = &types.OrderBook{
+			Exchange: row[1],
+			Market:   row[2],
+			Pair:     pair,
+			Bids:     bids,
+			Asks:     asks,
+			Time:     exchangeTime,
+		}
+		return record, nil
+	}
+
+	prices := make([]decimal.Decimal, 3)
+	for i, column := range row[7:10] {
+		if prices[i], err = decimal.NewFromString(column); err != nil {
+			return nil, err
+		}
+	}
+
+	record.Ticker = &types.Ticker{
+		Exchange:   row[1],
+		Market:     row[2],
+		Pair:       pair,
+		Bid:        prices[0],
+		Ask:        prices[1],
+		Last:       prices[2],
+		Time:       exchangeTime,
+		ReceivedAt: record.ReceivedAt,
+	}
+	return record, nil
+}
+
+func parseLevels(column string) ([]*types.PriceLevel, error) {
+	if column == "" {
+		return nil, nil
+	}
+
+	packed := strings.Split(column, "|")
+	levels := make([]*types.PriceLevel, len(packed))
+	for i, level := range packed {
+		parts := strings.SplitN(level, "@", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid price level: %s", level)
+		}
+
+		price, err := decimal.NewFromString(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		quantity, err := decimal.NewFromString(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		levels[i] = &types.PriceLevel{Price: price, Quantity: quantity}
+	}
+
+	return levels, nil
+}
+
+// MultiReader merges records of several files by local receive time
+type MultiReader struct {
+	readers []*Reader
+	heads   []*Record
+}
+
+// OpenAll returns a MultiReader of the recorded files, each of them must be in order already
+func OpenAll(paths []string) (*MultiReader, error) {
+	m := &MultiReader{
+		readers: make([]*Reader, 0, len(paths)),
+		heads:   make([]*Record, 0, len(paths)),
+	}
+
+	for _, path := range paths {
+		r, err := Open(path)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.readers = append(m.readers, r)
+
+		record, err := r.Next()
+		if err == io.EOF {
+			record, err = nil, nil
+		}
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.heads = append(m.heads, record)
+	}
+
+	return m, nil
+}
+
+// Next returns the earliest record not read yet, io.EOF once all records were read
+func (m *MultiReader) Next() (*Record, error) {
+	i := -1
+	for j, record := range m.heads {
+		if record != nil && (i < 0 || record.ReceivedAt.Before(m.heads[i].ReceivedAt)) {
+			i = j
+		}
+	}
+	if i < 0 {
+		return nil, io.EOF
+	}
+
+	record := m.heads[i]
+	next, err := m.readers[i].Next()
+	if err == io.EOF {
+		next, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.heads[i] = next
+
+	return record, nil
+}
+
+// Close closes all files
+func (m *MultiReader) Close() error {
+	var err error
+	for _, r := range m.readers {
+		if closeErr := r.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+// Package recorder writes received market data to rotated, compressed files and reads them back
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// RecordType tells what market data a record holds
+type RecordType string
+
+const (
+	// TickerRecord holds a ticker
+	TickerRecord RecordType = "ticker"
+	// OrderBookRecord holds an order book snapshot
+	OrderBookRecord RecordType = "order_book"
+)
+
+// ErrUnknownFormat is returned when the configured format is neither JSON Lines nor CSV
+var ErrUnknownFormat = errors.New("Unknown recording format")
+
+// timeLayout keeps nanoseconds so records received in a burst stay ordered
+const timeLayout = time.RFC3339Nano
+
+// csvHeader names columns of CSV files, exchange_time is the ticker or order book time
+var csvHeader = []string{
+	"type", "exchange", "market", "base", "quote", "exchange_time", "received_at",
+	"bid", "ask", "last", "bids", "asks",
+}
+
+// Record is a single line of a JSON Lines file
+type Record struct {
+	Type       RecordType       `json:"type"`
+	ReceivedAt time.Time        `json:"received_at"` // local time the bot received the data
+	Ticker     *types.Ticker    `json:"ticker,omitempty"`
+	OrderBook  *types.OrderBook `json:"order_book,omitempty"`
+}
+
+// Recorder writes market data into gzip compressed files rotated by size and age, safe for concurrent use
+type Recorder struct {
+	cnf       *Config
+	file      *os.File
+	counter   *countingWriter
+	gz        *gzip.Writer
+	csv       *csv.Writer
+	json      *json.Encoder
+	openedAt  time.Time
+	flushedAt time.Time
+	mu        *sync.Mutex
+}
+
+// New returns new Recorder instance, the first file is opened with the first record
+func New(cnf *Config) *Recorder {
+	if cnf.Prefix == "" {
+		cnf.Prefix = DefaultPrefix
+	}
+	if cnf.Format == "" {
+		cnf.Format = JSONLines
+	}
+
+	return &Recorder{
+		cnf: cnf,
+		mu:  new(sync.Mutex),
+	}
+}
+
+// RecordTicker writes the ticker received at the local time
+func (r *Recorder) RecordTicker(ticker *types.Ticker, receivedAt time.Time) error {
+	return r.write(&Record{Type: TickerRecord, ReceivedAt: receivedAt, Ticker: ticker})
+}
+
+// RecordOrderBook writes the order book snapshot received at the local time
+func (r *Recorder) RecordOrderBook(book *types.OrderBook, receivedAt time.Time) error {
+	return r.write(&Record{Type: OrderBookRecord, ReceivedAt: receivedAt, OrderBook: book})
+}
+
+// Close flushes buffered records and closes the current file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.closeFile()
+}
+
+func (r *Recorder) write(record *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.file != nil && r.needsRotation(now) {
+		if err := r.closeFile(); err != nil {
+			return err
+		}
+	}
+
+	if r.file == nil {
+		if err := r.openFile(now); err != nil {
+			return err
+		}
+	}
+
+	if r.cnf.Format == CSV {
+		if err := r.csv.Write(csvRow(record)); err != nil {
+			return err
+		}
+	} else if err := r.json.Encode(record); err != nil {
+		return err
+	}
+
+	if now.Sub(r.flushedAt) >= r.cnf.FlushInterval {
+		return r.flush(now)
+	}
+
+	return nil
+}
+
+func (r *Recorder) needsRotation(now time.Time) bool {
+	if r.cnf.MaxSize > 0 && r.counter.n >= r.cnf.MaxSize {
+		return true
+	}
+	return r.cnf.RotateInterval > 0 && now.Sub(r.openedAt) >= r.cnf.RotateInterval
+}
+
+func (r *Recorder) openFile(now time.Time) error {
+	if r.cnf.Format != JSONLines && r.cnf.Format != CSV {
+		return fmt.Errorf("%v: %s", ErrUnknownFormat, r.cnf.Format)
+	}
+
+	if r.cnf.Dir != "" {
+		if err := os.MkdirAll(r.cnf.Dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	// Files rotated by size within the same millisecond get a sequence suffix
+	stamp := now.UTC().Format("20060102T150405.000")
+	name := fmt.Sprintf("%s-%s.%s.gz", r.cnf.Prefix, stamp, r.cnf.Format)
+	file, err := os.OpenFile(filepath.Join(r.cnf.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	for seq := 1; os.IsExist(err); seq++ {
+		name = fmt.Sprintf("%s-%s-%d.%s.gz", r.cnf.Prefix, stamp, seq, r.cnf.Format)
+		file, err = os.OpenFile(filepath.Join(r.cnf.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.file = file
+	r.counter = &countingWriter{w: file}
+	r.gz = gzip.NewWriter(r.counter)
+	r.openedAt = now
+	r.flushedAt = now
+
+	if r.cnf.Format == CSV {
+		r.csv = csv.NewWriter(r.gz)
+		return r.csv.Write(csvHeader)
+	}
+
+	r.json = json.NewEncoder(r.gz)
+	return nil
+}
+
+func (r *Recorder) flush(now time.Time) error {
+	if r.csv != nil {
+		r.csv.Flush()
+		if err := r.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	r.flushedAt = now
+	return r.gz.Flush()
+}
+
+func (r *Recorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+
+	var err error
+	if r.csv != nil {
+		r.csv.Flush()
+		err = r.csv.Error()
+	}
+	if gzErr := r.gz.Close(); err == nil {
+		err = gzErr
+	}
+	if fileErr := r.file.Close(); err == nil {
+		err = fileErr
+	}
+
+	r.file, r.counter, r.gz, r.csv, r.json = nil, nil, nil, nil, nil
+	return err
+}
+
+func csvRow(record *Record) []string {
+	if record.Type == OrderBookRecord {
+		b := record.OrderBook
+		return []string{
+			string(record.Type), b.Exchange, b.Market, b.Pair.Base, b.Pair.Quote,
+			b.Time.Format(timeLayout), record.ReceivedAt.Format(timeLayout),
+			"", "", "", formatLevels(b.Bids), formatLevels(b.Asks),
+		}
+	}
+
+	t := record.Ticker
+	return []string{
+		string(record.Type), t.Exchange, t.Market, t.Pair.Base, t.Pair.Quote,
+		t.Time.Format(timeLayout), record.ReceivedAt.Format(timeLayout),
+		t.Bid.String(), t.Ask.String(), t.Last.String(), "", "",
+	}
+}
+
+// formatLevels packs price levels into a single column, e.g. 0.0101@2.5|0.0102@1
+func formatLevels(levels []*types.PriceLevel) string {
+	packed := make([]string, len(levels))
+	for i, level := range levels {
+		packed[i] = level.Price.String() + "@" + level.Quantity.String()
+	}
+	return strings.Join(packed, "|")
+}
+
+// countingWriter counts compressed bytes written to the file
+type countingWriter struct {
+	w *os.File
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
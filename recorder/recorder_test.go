@@ -0,0 +1,191 @@
+package recorder
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundTrip(t *testing.T) {
+	pair := types.Pair{Base: "LTC", Quote: "BTC"}
+	start := time.Date(2018, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	ticker := func(exchange, bid string, i int) *types.Ticker {
+		return &types.Ticker{
+			Exchange:   exchange,
+			Market:     "BTC-LTC",
+			Pair:       pair,
+			Bid:        mustDecimal(bid),
+			Ask:        mustDecimal("0.0102"),
+			Last:       mustDecimal("0.0101"),
+			Time:       start.Add(time.Duration(i) * time.Millisecond),
+			ReceivedAt: start.Add(time.Duration(i)*time.Millisecond + time.Microsecond),
+		}
+	}
+	book := &types.OrderBook{
+		Exchange: "bittrex",
+		Market:   "BTC-LTC",
+		Pair:     pair,
+		Bids:     []*types.PriceLevel{{Price: mustDecimal("0.01"), Quantity: mustDecimal("2.5")}},
+		Asks:     []*types.PriceLevel{{Price: mustDecimal("0.0102"), Quantity: mustDecimal("1")}, {Price: mustDecimal("0.0103"), Quantity: mustDecimal("4")}},
+		Time:     start.Add(2 * time.Millisecond),
+	}
+	records := []*Record{
+		{Type: TickerRecord, ReceivedAt: start.Add(time.Microsecond), Ticker: ticker("bittrex", "0.01", 0)},
+		{Type: TickerRecord, ReceivedAt: start.Add(time.Millisecond + time.Microsecond), Ticker: ticker("poloniex", "0.0099", 1)},
+		{Type: OrderBookRecord, ReceivedAt: start.Add(2*time.Millisecond + time.Microsecond), OrderBook: book},
+		{Type: TickerRecord, ReceivedAt: start.Add(3*time.Millisecond + time.Microsecond), Ticker: ticker("bittrex", "0.0101", 3)},
+	}
+
+	tests := []struct {
+		name    string
+		format  Format
+		maxSize int64
+		files   int
+	}{
+		{
+			name:   "JSON Lines in a single file",
+			format: JSONLines,
+			files:  1,
+		},
+		{
+			name:   "CSV in a single file",
+			format: CSV,
+			files:  1,
+		},
+		{
+			// Every record fills a file, they are rotated faster than file names change
+			name:    "JSON Lines rotated after every record",
+			format:  JSONLines,
+			maxSize: 1,
+			files:   len(records),
+		},
+		{
+			name:    "CSV rotated after every record",
+			format:  CSV,
+			maxSize: 1,
+			files:   len(records),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "recorder")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			// Missing directories are created
+			recordDir := filepath.Join(dir, "ticks", "2018")
+			r := New(&Config{Dir: recordDir, Format: tt.format, MaxSize: tt.maxSize})
+			for _, record := range records {
+				if record.Type == TickerRecord {
+					err = r.RecordTicker(record.Ticker, record.ReceivedAt)
+				} else {
+					err = r.RecordOrderBook(record.OrderBook, record.ReceivedAt)
+				}
+				if err != nil {
+					t.Fatalf("record error: %v", err)
+				}
+			}
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			paths, err := filepath.Glob(filepath.Join(recordDir, DefaultPrefix+"-*."+string(tt.format)+".gz"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != tt.files {
+				t.Fatalf("got %d files, want %d", len(paths), tt.files)
+			}
+
+			m, err := OpenAll(paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+
+			for i, want := range records {
+				got, err := m.Next()
+				if err != nil {
+					t.Fatalf("record %d error: %v", i, err)
+				}
+				assertRecord(t, i, got, want)
+			}
+			if _, err := m.Next(); err != io.EOF {
+				t.Errorf("error after last record = %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := New(&Config{Dir: dir, Format: "xml"})
+	if err := r.RecordTicker(&types.Ticker{}, time.Now()); err == nil {
+		t.Error("ticker recorded in unknown format")
+	}
+
+	if _, err := Open(filepath.Join(dir, "ticks.xml.gz")); err == nil {
+		t.Error("file of unknown format opened")
+	}
+}
+
+func assertRecord(t *testing.T, i int, got, want *Record) {
+	t.Helper()
+
+	if got.Type != want.Type || !got.ReceivedAt.Equal(want.ReceivedAt) {
+		t.Errorf("record %d = %s received at %s, want %s received at %s", i, got.Type, got.ReceivedAt, want.Type, want.ReceivedAt)
+		return
+	}
+
+	if want.Type == OrderBookRecord {
+		g, w := got.OrderBook, want.OrderBook
+		if g.Exchange != w.Exchange || g.Market != w.Market || g.Pair != w.Pair || !g.Time.Equal(w.Time) {
+			t.Errorf("record %d order book = %+v, want %+v", i, g, w)
+		}
+		for _, side := range []struct {
+			name      string
+			got, want []*types.PriceLevel
+		}{{"bids", g.Bids, w.Bids}, {"asks", g.Asks, w.Asks}} {
+			if len(side.got) != len(side.want) {
+				t.Errorf("record %d has %d %s, want %d", i, len(side.got), side.name, len(side.want))
+				continue
+			}
+			for j := range side.want {
+				if !side.got[j].Price.Equal(side.want[j].Price) || !side.got[j].Quantity.Equal(side.want[j].Quantity) {
+					t.Errorf("record %d %s level %d = %+v, want %+v", i, side.name, j, side.got[j], side.want[j])
+				}
+			}
+		}
+		return
+	}
+
+	g, w := got.Ticker, want.Ticker
+	if g.Exchange != w.Exchange || g.Market != w.Market || g.Pair != w.Pair || !g.Time.Equal(w.Time) || !g.ReceivedAt.Equal(w.ReceivedAt) {
+		t.Errorf("record %d ticker = %+v, want %+v", i, g, w)
+	}
+	if !g.Bid.Equal(w.Bid) || !g.Ask.Equal(w.Ask) || !g.Last.Equal(w.Last) {
+		t.Errorf("record %d prices = %s/%s/%s, want %s/%s/%s", i, g.Bid, g.Ask, g.Last, w.Bid, w.Ask, w.Last)
+	}
+}
+
+func mustDecimal(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
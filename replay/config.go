@@ -0,0 +1,15 @@
+package replay
+
+// Config stores replay options
+type Config struct {
+	Files []string // recorded files, records of all files are merged by local receive time
+	// Speed scales original inter-arrival times, 1 replays in real time, 2 twice as fast,
+	// zero as fast as possible
+	Speed float64
+	// OrderBooks replays order book snapshots too, replay waits until RunOrderBooks
+	// was called on every exchange
+	OrderBooks bool
+	// KeepTimestamps keeps recorded exchange timestamps, otherwise they are shifted
+	// so the data is as old on delivery as it was when it was recorded
+	KeepTimestamps bool
+}
@@ -0,0 +1,42 @@
+// Package replay feeds recorded market data to the bot as if it came from live exchanges
+package replay
+
+import (
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// Exchange emits recorded tickers and order books of a single exchange as the sequencer delivers them
+type Exchange struct {
+	name      string
+	sequencer *Sequencer
+	tickers   chan *types.Ticker
+	books     chan *types.OrderBook
+}
+
+// GetName returns name of the recorded exchange so replayed data is indistinguishable from live data
+func (e *Exchange) GetName() string {
+	return e.name
+}
+
+// Run emits recorded tickers until quit, replay starts once all exchanges of the sequencer run
+func (e *Exchange) Run(tickers chan *types.Ticker) error {
+	e.sequencer.register(e, tickers, nil)
+	<-e.sequencer.quit
+
+	return nil
+}
+
+// RunOrderBooks emits recorded order book snapshots until quit
+func (e *Exchange) RunOrderBooks(books chan *types.OrderBook) error {
+	e.sequencer.register(e, nil, books)
+	<-e.sequencer.quit
+
+	return nil
+}
+
+// Quit stops the replay of all exchanges sharing the sequencer
+func (e *Exchange) Quit() error {
+	e.sequencer.stop()
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package replay
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/recorder"
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+func TestSequencer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each exchange is recorded into its own file, receive times interleave
+	// and the last two records were received at the same time
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	received := map[string][]int{
+		"bittrex":  {0, 3, 4, 9},
+		"poloniex": {1, 2, 5, 9},
+	}
+	var files []string
+	for _, exchange := range []string{"bittrex", "poloniex"} {
+		r := recorder.New(&recorder.Config{Dir: dir, Prefix: exchange})
+		for i, ms := range received[exchange] {
+			receivedAt := start.Add(time.Duration(ms) * time.Millisecond)
+			ticker := &types.Ticker{
+				Exchange:   exchange,
+				Market:     "BTC-LTC",
+				Pair:       types.Pair{Base: "LTC", Quote: "BTC"},
+				Bid:        decimal.New(int64(i), 0),
+				Ask:        decimal.New(int64(i+1), 0),
+				Last:       decimal.New(int64(i), 0),
+				Time:       receivedAt.Add(-time.Millisecond),
+				ReceivedAt: receivedAt,
+			}
+			if err := r.RecordTicker(ticker, receivedAt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		paths, err := filepath.Glob(filepath.Join(dir, exchange+"-*"))
+		if err != nil || len(paths) != 1 {
+			t.Fatalf("recorded files of %s: %v (%v)", exchange, paths, err)
+		}
+		files = append(files, paths...)
+	}
+
+	want := []string{
+		"bittrex 0 at 0ms",
+		"poloniex 0 at 1ms",
+		"poloniex 1 at 2ms",
+		"bittrex 1 at 3ms",
+		"bittrex 2 at 4ms",
+		"poloniex 2 at 5ms",
+		"bittrex 3 at 9ms",
+		"poloniex 3 at 9ms",
+	}
+
+	// As fast as possible is deterministic, every run emits the same sequence
+	for run := 0; run < 3; run++ {
+		got := replayAll(t, &Config{Files: files, KeepTimestamps: true}, start)
+		if len(got) != len(want) {
+			t.Fatalf("run %d emitted %d tickers, want %d: %v", run, len(got), len(want), got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("run %d ticker %d = %s, want %s", run, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestSequencerShiftsTimestamps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recordedAt := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := recorder.New(&recorder.Config{Dir: dir})
+	ticker := &types.Ticker{Exchange: "bittrex", Time: recordedAt.Add(-2 * time.Second)}
+	if err := r.RecordTicker(ticker, recordedAt); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+
+	s := NewSequencer(&Config{Files: files})
+	e := s.Exchange("bittrex")
+	tickers := make(chan *types.Ticker, 1)
+	go e.Run(tickers)
+	defer e.Quit()
+
+	replayed := <-tickers
+	if age := replayed.ReceivedAt.Sub(replayed.Time); age != 2*time.Second {
+		t.Errorf("replayed ticker is %s old, want 2s", age)
+	}
+	if time.Since(replayed.ReceivedAt) > time.Minute {
+		t.Errorf("replayed ticker received at %s, want now", replayed.ReceivedAt)
+	}
+}
+
+// replayAll runs replay exchanges of all recorded exchanges sharing one sequencer
+// and returns emitted tickers in order of delivery
+func replayAll(t *testing.T, cnf *Config, start time.Time) []string {
+	t.Helper()
+
+	s := NewSequencer(cnf)
+	exchanges := []*Exchange{s.Exchange("bittrex"), s.Exchange("poloniex")}
+
+	tickers := make(chan *types.Ticker)
+	var wg sync.WaitGroup
+	for _, e := range exchanges {
+		wg.Add(1)
+		go func(e *Exchange) {
+			defer wg.Done()
+			e.Run(tickers)
+		}(e)
+	}
+
+	var emitted []string
+	for done := false; !done; {
+		select {
+		case ticker := <-tickers:
+			emitted = append(emitted, fmt.Sprintf(
+				"%s %s at %dms",
+				ticker.Exchange,
+				ticker.Bid,
+				ticker.ReceivedAt.Sub(start)/time.Millisecond,
+			))
+		case <-s.Done():
+			done = true
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Quitting one exchange stops all of them, quitting twice does not block
+	exchanges[0].Quit()
+	exchanges[1].Quit()
+	wg.Wait()
+
+	return emitted
+}
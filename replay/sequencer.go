@@ -0,0 +1,187 @@
+package replay
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/recorder"
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+// Sequencer is the shared clock of replay exchanges, it merges recorded files and
+// delivers every record to its exchange in order of local receive time so
+// relationships between exchanges are preserved
+type Sequencer struct {
+	cnf       *Config
+	exchanges map[string]*Exchange
+	ready     chan int
+	quit      chan int
+	quitOnce  *sync.Once
+	done      chan int
+	err       error
+	mu        *sync.Mutex
+}
+
+// NewSequencer returns new Sequencer instance
+func NewSequencer(cnf *Config) *Sequencer {
+	return &Sequencer{
+		cnf:       cnf,
+		exchanges: make(map[string]*Exchange),
+		ready:     make(chan int),
+		quit:      make(chan int),
+		quitOnce:  new(sync.Once),
+		done:      make(chan int),
+		mu:        new(sync.Mutex),
+	}
+}
+
+// Exchange returns the replay exchange of recorded data of the named exchange,
+// all exchanges must be created before any of them runs
+func (s *Sequencer) Exchange(name string) *Exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.exchanges[name]
+	if !ok {
+		e = &Exchange{name: name, sequencer: s}
+		s.exchanges[name] = e
+	}
+
+	return e
+}
+
+// Done is closed once all records were delivered or replay stopped
+func (s *Sequencer) Done() <-chan int {
+	return s.done
+}
+
+// Err returns error which stopped the replay early
+func (s *Sequencer) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// register stores the channel of a running exchange and starts the replay once all exchanges run
+func (s *Sequencer) register(e *Exchange, tickers chan *types.Ticker, books chan *types.OrderBook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tickers != nil {
+		e.tickers = tickers
+	}
+	if books != nil {
+		e.books = books
+	}
+
+	for _, e := range s.exchanges {
+		if e.tickers == nil || (s.cnf.OrderBooks && e.books == nil) {
+			return
+		}
+	}
+
+	select {
+	case <-s.ready:
+	default:
+		close(s.ready)
+		go s.run()
+	}
+}
+
+func (s *Sequencer) stop() {
+	s.quitOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
+func (s *Sequencer) run() {
+	defer close(s.done)
+
+	if err := s.replay(); err != nil {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+	}
+}
+
+func (s *Sequencer) replay() error {
+	records, err := recorder.OpenAll(s.cnf.Files)
+	if err != nil {
+		return err
+	}
+	defer records.Close()
+
+	var start, first time.Time
+	for {
+		record, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if start.IsZero() {
+			start, first = time.Now(), record.ReceivedAt
+		}
+
+		// Schedule against the start so delays do not accumulate
+		if s.cnf.Speed > 0 {
+			at := start.Add(time.Duration(float64(record.ReceivedAt.Sub(first)) / s.cnf.Speed))
+			select {
+			case <-s.quit:
+				return nil
+			case <-time.After(time.Until(at)):
+			}
+		}
+
+		if !s.deliver(record) {
+			return nil
+		}
+	}
+}
+
+// deliver sends the record to its exchange, it returns false when replay was stopped
+func (s *Sequencer) deliver(record *recorder.Record) bool {
+	now := time.Now()
+
+	switch record.Type {
+	case recorder.TickerRecord:
+		e, ok := s.exchanges[record.Ticker.Exchange]
+		if !ok {
+			return true
+		}
+
+		ticker := *record.Ticker
+		if !s.cnf.KeepTimestamps {
+			ticker.Time = now.Add(ticker.Time.Sub(record.ReceivedAt))
+			ticker.ReceivedAt = now
+		}
+
+		select {
+		case e.tickers <- &ticker:
+		case <-s.quit:
+			return false
+		}
+	case recorder.OrderBookRecord:
+		e, ok := s.exchanges[record.OrderBook.Exchange]
+		if !ok || !s.cnf.OrderBooks {
+			return true
+		}
+
+		book := *record.OrderBook
+		if !s.cnf.KeepTimestamps {
+			book.Time = now.Add(book.Time.Sub(record.ReceivedAt))
+		}
+
+		select {
+		case e.books <- &book:
+		case <-s.quit:
+			return false
+		}
+	}
+
+	return true
+}
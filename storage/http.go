@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Handler serves history as JSON arrays at /opportunities, /orders, /fills, /balances
+// and /transfers, from and to query parameters in RFC 3339 limit the time range and
+// exchange limits balances to one exchange
+func (db *DB) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/opportunities", db.serve(func(req *http.Request, from, to time.Time) (interface{}, error) {
+		return db.Opportunities(from, to)
+	}))
+	mux.HandleFunc("/orders", db.serve(func(req *http.Request, from, to time.Time) (interface{}, error) {
+		return db.Orders(from, to)
+	}))
+	mux.HandleFunc("/fills", db.serve(func(req *http.Request, from, to time.Time) (interface{}, error) {
+		return db.Fills(from, to)
+	}))
+	mux.HandleFunc("/balances", db.serve(func(req *http.Request, from, to time.Time) (interface{}, error) {
+		return db.Balances(req.URL.Query().Get("exchange"), from, to)
+	}))
+	mux.HandleFunc("/transfers", db.serve(func(req *http.Request, from, to time.Time) (interface{}, error) {
+		return db.Transfers(from, to)
+	}))
+	return mux
+}
+
+func (db *DB) serve(query func(req *http.Request, from, to time.Time) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		from, err := parseTime(req, "from")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := parseTime(req, "to")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		records, err := query(req, from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Empty tables are served as an empty array rather than null
+		data, err := json.Marshal(records)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if string(data) == "null" {
+			data = []byte("[]")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+// parseTime returns zero time when the query parameter is missing
+func parseTime(req *http.Request, name string) (time.Time, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s time: %v", name, err)
+	}
+	return t, nil
+}
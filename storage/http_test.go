@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestHandler(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	err = db.SaveBalances([]*BalanceSnapshot{
+		{Exchange: "bittrex", Currency: "BTC", Total: decimal.New(1, 0), Time: start},
+		{Exchange: "poloniex", Currency: "BTC", Total: decimal.New(2, 0), Time: start},
+		{Exchange: "bittrex", Currency: "BTC", Total: decimal.New(3, 0), Time: start.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url    string
+		status int
+		totals []string
+	}{
+		{url: "/balances", status: http.StatusOK, totals: []string{"1", "2", "3"}},
+		{url: "/balances?exchange=bittrex", status: http.StatusOK, totals: []string{"1", "3"}},
+		{url: "/balances?from=2018-01-02T03:30:00Z", status: http.StatusOK, totals: []string{"3"}},
+		{url: "/balances?to=2018-01-02T03:04:05Z", status: http.StatusOK, totals: []string{"1", "2"}},
+		{url: "/balances?from=yesterday", status: http.StatusBadRequest},
+		{url: "/fills", status: http.StatusOK, totals: []string{}},
+		{url: "/unknown", status: http.StatusNotFound},
+	}
+
+	handler := db.Handler()
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s status = %d, want %d", tt.url, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+
+		// Empty tables are an empty array
+		var records []*BalanceSnapshot
+		if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || records == nil {
+			t.Errorf("%s body %s: %v", tt.url, rec.Body.String(), err)
+			continue
+		}
+		if len(records) != len(tt.totals) {
+			t.Errorf("%s returned %d records, want %d", tt.url, len(records), len(tt.totals))
+			continue
+		}
+		for i, total := range tt.totals {
+			if records[i].Total.String() != total {
+				t.Errorf("%s record %d total = %s, want %s", tt.url, i, records[i].Total, total)
+			}
+		}
+	}
+}
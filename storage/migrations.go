@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	tableOpportunities = "opportunities"
+	tableOrders        = "orders"
+	tableFills         = "fills"
+	tableBalances      = "balances"
+	tableTransfers     = "transfers"
+)
+
+// Migration upgrades files in the database directory to the next schema version,
+// it must keep existing records so upgrades do not lose history
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(dir string) error
+}
+
+// migrations are applied in order, append new ones at the end and never change released ones
+var migrations = []*Migration{
+	{
+		Version:     1,
+		Description: "Create opportunities, orders, fills, balances and transfers tables",
+		Up:          createTables(tableOpportunities, tableOrders, tableFills, tableBalances, tableTransfers),
+	},
+}
+
+// tables lists tables of the latest schema version
+var tables = []string{tableOpportunities, tableOrders, tableFills, tableBalances, tableTransfers}
+
+// LatestVersion is the schema version this build of the package writes
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+func createTables(names ...string) func(dir string) error {
+	return func(dir string) error {
+		for _, name := range names {
+			f, err := os.OpenFile(tablePath(dir, name), os.O_WRONLY|os.O_CREATE, 0644)
+			if err != nil {
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func tablePath(dir, table string) string {
+	return filepath.Join(dir, table+".jsonl")
+}
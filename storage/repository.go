@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+// Opportunity is a detected cross-exchange spread opportunity
+type Opportunity struct {
+	Pair           types.Pair      `json:"pair"`
+	BuyExchange    string          `json:"buy_exchange"`
+	SellExchange   string          `json:"sell_exchange"`
+	BuyPrice       decimal.Decimal `json:"buy_price"`
+	SellPrice      decimal.Decimal `json:"sell_price"`
+	GrossSpreadBps decimal.Decimal `json:"gross_spread_bps"`
+	NetSpreadBps   decimal.Decimal `json:"net_spread_bps"`
+	Quantity       decimal.Decimal `json:"quantity"`
+	DetectedAt     time.Time       `json:"detected_at"`
+}
+
+// Order is the state of an order, saving it again records a newer state
+type Order struct {
+	ExecutionID    string            `json:"execution_id"`
+	ID             string            `json:"id"`
+	Exchange       string            `json:"exchange"`
+	Pair           types.Pair        `json:"pair"`
+	Side           types.Side        `json:"side"`
+	Price          decimal.Decimal   `json:"price"`
+	Quantity       decimal.Decimal   `json:"quantity"`
+	FilledQuantity decimal.Decimal   `json:"filled_quantity"`
+	AveragePrice   decimal.Decimal   `json:"average_price"`
+	Fee            decimal.Decimal   `json:"fee"`
+	Status         types.OrderStatus `json:"status"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Fill is quantity traded by an order
+type Fill struct {
+	OrderID  string          `json:"order_id"`
+	Exchange string          `json:"exchange"`
+	Pair     types.Pair      `json:"pair"`
+	Side     types.Side      `json:"side"`
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Fee      decimal.Decimal `json:"fee"`
+	Time     time.Time       `json:"time"`
+}
+
+// BalanceSnapshot is a balance of a currency held on an exchange at a time
+type BalanceSnapshot struct {
+	Exchange  string          `json:"exchange"`
+	Currency  string          `json:"currency"`
+	Available decimal.Decimal `json:"available"`
+	Pending   decimal.Decimal `json:"pending"`
+	Total     decimal.Decimal `json:"total"`
+	Time      time.Time       `json:"time"`
+}
+
+// Transfer is a rebalancing transfer between exchanges
+type Transfer struct {
+	Currency     string          `json:"currency"`
+	Asset        string          `json:"asset"`
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Amount       decimal.Decimal `json:"amount"`
+	AssetAmount  decimal.Decimal `json:"asset_amount"`
+	Fee          decimal.Decimal `json:"fee"`
+	Status       string          `json:"status"`
+	WithdrawalID string          `json:"withdrawal_id,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// SaveOpportunity ...
+func (db *DB) SaveOpportunity(o *Opportunity) error {
+	return db.insert(tableOpportunities, o)
+}
+
+// Opportunities returns opportunities detected within the time range, zero times mean unbounded
+func (db *DB) Opportunities(from, to time.Time) ([]*Opportunity, error) {
+	var opportunities []*Opportunity
+	err := db.scan(tableOpportunities, func(data []byte) error {
+		o := new(Opportunity)
+		if err := json.Unmarshal(data, o); err != nil {
+			return err
+		}
+		if inRange(o.DetectedAt, from, to) {
+			opportunities = append(opportunities, o)
+		}
+		return nil
+	})
+
+	return opportunities, err
+}
+
+// SaveOrder records the latest state of an order
+func (db *DB) SaveOrder(o *Order) error {
+	return db.insert(tableOrders, o)
+}
+
+// Orders returns latest states of orders created within the time range, zero times mean unbounded
+func (db *DB) Orders(from, to time.Time) ([]*Order, error) {
+	var (
+		orders []*Order
+		latest = make(map[string]int)
+	)
+	err := db.scan(tableOrders, func(data []byte) error {
+		o := new(Order)
+		if err := json.Unmarshal(data, o); err != nil {
+			return err
+		}
+		if !inRange(o.CreatedAt, from, to) {
+			return nil
+		}
+
+		key := o.Exchange + ":" + o.ID
+		if i, ok := latest[key]; ok {
+			orders[i] = o
+			return nil
+		}
+		latest[key] = len(orders)
+		orders = append(orders, o)
+		return nil
+	})
+
+	return orders, err
+}
+
+// Order returns the latest state of an order, nil if it was never saved
+func (db *DB) Order(exchange, id string) (*Order, error) {
+	var order *Order
+	err := db.scan(tableOrders, func(data []byte) error {
+		o := new(Order)
+		if err := json.Unmarshal(data, o); err != nil {
+			return err
+		}
+		if o.Exchange == exchange && o.ID == id {
+			order = o
+		}
+		return nil
+	})
+
+	return order, err
+}
+
+// SaveFill ...
+func (db *DB) SaveFill(f *Fill) error {
+	return db.insert(tableFills, f)
+}
+
+// Fills returns fills within the time range, zero times mean unbounded
+func (db *DB) Fills(from, to time.Time) ([]*Fill, error) {
+	var fills []*Fill
+	err := db.scan(tableFills, func(data []byte) error {
+		f := new(Fill)
+		if err := json.Unmarshal(data, f); err != nil {
+			return err
+		}
+		if inRange(f.Time, from, to) {
+			fills = append(fills, f)
+		}
+		return nil
+	})
+
+	return fills, err
+}
+
+// SaveBalances records a snapshot of balances
+func (db *DB) SaveBalances(balances []*BalanceSnapshot) error {
+	for _, b := range balances {
+		if err := db.insert(tableBalances, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Balances returns balance snapshots of an exchange within the time range, empty
+// exchange means all exchanges and zero times mean unbounded
+func (db *DB) Balances(exchange string, from, to time.Time) ([]*BalanceSnapshot, error) {
+	var balances []*BalanceSnapshot
+	err := db.scan(tableBalances, func(data []byte) error {
+		b := new(BalanceSnapshot)
+		if err := json.Unmarshal(data, b); err != nil {
+			return err
+		}
+		if (exchange == "" || b.Exchange == exchange) && inRange(b.Time, from, to) {
+			balances = append(balances, b)
+		}
+		return nil
+	})
+
+	return balances, err
+}
+
+// SaveTransfer ...
+func (db *DB) SaveTransfer(t *Transfer) error {
+	return db.insert(tableTransfers, t)
+}
+
+// Transfers returns transfers created within the time range, zero times mean unbounded
+func (db *DB) Transfers(from, to time.Time) ([]*Transfer, error) {
+	var transfers []*Transfer
+	err := db.scan(tableTransfers, func(data []byte) error {
+		t := new(Transfer)
+		if err := json.Unmarshal(data, t); err != nil {
+			return err
+		}
+		if inRange(t.CreatedAt, from, to) {
+			transfers = append(transfers, t)
+		}
+		return nil
+	})
+
+	return transfers, err
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	return to.IsZero() || !t.After(to)
+}
@@ -0,0 +1,252 @@
+// Package storage is an embedded database of trading history with versioned schema migrations
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxRecordSize limits length of a single record, order book heavy records stay well below it
+const maxRecordSize = 1024 * 1024
+
+// ErrSchemaTooNew is returned when the database was written by a newer version
+// of the package, opening it could lose history
+var ErrSchemaTooNew = errors.New("Database schema is newer than supported")
+
+// schema is stored in schema.json of the database directory
+type schema struct {
+	Version int `json:"version"`
+}
+
+// DB is an embedded database of append-only JSON Lines tables in a single directory,
+// safe for concurrent use. Every record is synced to disk before the call returns,
+// a record torn by a crash while writing it is dropped when the database is opened.
+type DB struct {
+	dir     string
+	version int
+	files   map[string]*os.File
+	mu      *sync.RWMutex
+}
+
+// Open opens the database in the directory, creating it if needed, and migrates
+// it to the latest schema version
+func Open(dir string) (*DB, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		dir:   dir,
+		files: make(map[string]*os.File, len(tables)),
+		mu:    new(sync.RWMutex),
+	}
+
+	if err := db.migrate(); err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		// New records must not be appended to a torn one
+		if err := truncateTorn(tablePath(dir, table)); err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		f, err := os.OpenFile(tablePath(dir, table), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		db.files[table] = f
+	}
+
+	return db, nil
+}
+
+// Version returns the schema version of the database
+func (db *DB) Version() int {
+	return db.version
+}
+
+// Close syncs and closes all tables
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var err error
+	for _, f := range db.files {
+		if syncErr := f.Sync(); err == nil {
+			err = syncErr
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	db.files = nil
+
+	return err
+}
+
+// migrate applies migrations newer than the stored version one by one, the version
+// is stored after each of them so an interrupted upgrade resumes where it stopped
+func (db *DB) migrate() error {
+	current, err := readSchema(db.dir)
+	if err != nil {
+		return err
+	}
+
+	if current.Version > LatestVersion() {
+		return fmt.Errorf("%v: %d > %d", ErrSchemaTooNew, current.Version, LatestVersion())
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current.Version {
+			continue
+		}
+
+		if err := m.Up(db.dir); err != nil {
+			return fmt.Errorf("Migration %d (%s) error: %v", m.Version, m.Description, err)
+		}
+
+		current.Version = m.Version
+		if err := writeSchema(db.dir, current); err != nil {
+			return err
+		}
+	}
+
+	db.version = current.Version
+	return nil
+}
+
+// insert appends the record to the table and syncs it to disk
+func (db *DB) insert(table string, record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	f := db.files[table]
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// scan decodes every record of the table in order of insertion, inserts wait
+// until it finishes so it never reads a record being written
+func (db *DB) scan(table string, decode func(data []byte) error) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	f, err := os.Open(tablePath(db.dir, table))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	var torn error
+	for scanner.Scan() {
+		// Only the last line can be incomplete, e.g. when we crashed while writing it
+		if torn != nil {
+			return torn
+		}
+
+		if err := decode(scanner.Bytes()); err != nil {
+			torn = fmt.Errorf("Corrupted %s record: %v", table, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// truncateTorn cuts an incomplete last line off the file, it is left by a crash
+// while writing the record
+func truncateTorn(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Look for the last new line from the end of the file
+	size := info.Size()
+	end := size
+	buf := make([]byte, 4096)
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+
+	if end == size {
+		return nil
+	}
+
+	if err := f.Truncate(end); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func readSchema(dir string) (*schema, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "schema.json"))
+	if os.IsNotExist(err) {
+		return new(schema), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(schema)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// writeSchema replaces the schema file atomically
+func writeSchema(dir string, s *schema) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, "schema.json.tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, "schema.json"))
+}
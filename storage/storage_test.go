@@ -0,0 +1,352 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/arbitrage/types"
+	"github.com/shopspring/decimal"
+)
+
+func TestOpen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// A missing database is created at the latest version
+	path := filepath.Join(dir, "nested", "arbitrage.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Version() != LatestVersion() {
+		t.Errorf("version = %d, want %d", db.Version(), LatestVersion())
+	}
+	for _, table := range tables {
+		if _, err := os.Stat(tablePath(path, table)); err != nil {
+			t.Errorf("table %s: %v", table, err)
+		}
+	}
+	if s, err := readSchema(path); err != nil || s.Version != LatestVersion() {
+		t.Errorf("stored schema = %+v (%v), want version %d", s, err, LatestVersion())
+	}
+
+	fill := &Fill{OrderID: "order", Exchange: "bittrex", Quantity: decimal.New(1, 0), Time: time.Now()}
+	if err := db.SaveFill(fill); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening keeps history
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	fills, err := db.Fills(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 1 || fills[0].OrderID != "order" {
+		t.Errorf("fills = %v, want the saved fill", fills)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	defer func(original []*Migration) {
+		migrations = original
+	}(migrations)
+
+	var applied []int
+	migration := func(version int, err error) *Migration {
+		return &Migration{
+			Version:     version,
+			Description: "test",
+			Up: func(dir string) error {
+				if err == nil {
+					applied = append(applied, version)
+				}
+				return err
+			},
+		}
+	}
+
+	// Applied migrations are skipped and the version is stored after each of them
+	if err := writeSchema(dir, &schema{Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	migrations = []*Migration{migration(1, nil), migration(2, nil), migration(3, os.ErrPermission), migration(4, nil)}
+	db := &DB{dir: dir}
+	if err := db.migrate(); err == nil || !strings.Contains(err.Error(), "Migration 3 (test) error") {
+		t.Errorf("error = %v, want migration 3 error", err)
+	}
+	if len(applied) != 1 || applied[0] != 2 {
+		t.Errorf("applied = %v, want [2]", applied)
+	}
+	if s, _ := readSchema(dir); s.Version != 2 {
+		t.Errorf("stored version = %d, want 2", s.Version)
+	}
+
+	// An interrupted upgrade resumes where it stopped
+	applied = nil
+	migrations[2] = migration(3, nil)
+	if err := db.migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0] != 3 || applied[1] != 4 {
+		t.Errorf("applied = %v, want [3 4]", applied)
+	}
+	if db.Version() != 4 {
+		t.Errorf("version = %d, want 4", db.Version())
+	}
+}
+
+func TestSchemaTooNew(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	if err := writeSchema(dir, &schema{Version: LatestVersion() + 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Open(dir)
+	if err == nil || !strings.HasPrefix(err.Error(), ErrSchemaTooNew.Error()) {
+		t.Errorf("error = %v, want %v", err, ErrSchemaTooNew)
+	}
+	if s, _ := readSchema(dir); s.Version != LatestVersion()+1 {
+		t.Errorf("stored version = %d, want it unchanged", s.Version)
+	}
+}
+
+func TestTruncateTorn(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+
+	tests := []struct {
+		name    string
+		content *string // file is missing when nil
+		want    string
+	}{
+		{
+			name: "missing file",
+		},
+		{
+			name:    "empty file",
+			content: stringPtr(""),
+		},
+		{
+			name:    "complete records",
+			content: stringPtr("{}\n{}\n"),
+			want:    "{}\n{}\n",
+		},
+		{
+			name:    "torn last record",
+			content: stringPtr("{}\n{\"id\":"),
+			want:    "{}\n",
+		},
+		{
+			name:    "only a torn record",
+			content: stringPtr("{\"id\":"),
+		},
+		{
+			name:    "torn record longer than the read buffer",
+			content: stringPtr("{}\n" + long),
+			want:    "{}\n",
+		},
+		{
+			name:    "complete record longer than the read buffer",
+			content: stringPtr(long + "\n" + long),
+			want:    long + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "table.jsonl")
+			if tt.content != nil {
+				if err := ioutil.WriteFile(path, []byte(*tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := truncateTorn(path); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if tt.content == nil {
+				if !os.IsNotExist(err) {
+					t.Errorf("error = %v, want the file to stay missing", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("content = %.40q (%d bytes), want %.40q (%d bytes)", data, len(data), tt.want, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestTornRecordDropped(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveOrder(&Order{ID: "a", Exchange: "bittrex", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// Crash while writing the next record
+	f, err := os.OpenFile(tablePath(dir, tableOrders), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"b","exch`)
+	f.Close()
+
+	db, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.SaveOrder(&Order{ID: "c", Exchange: "bittrex", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := db.Orders(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].ID != "a" || orders[1].ID != "c" {
+		t.Errorf("orders = %v, want a and c", orders)
+	}
+}
+
+func TestOrders(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	pair := types.Pair{Base: "LTC", Quote: "BTC"}
+	states := []*Order{
+		{ID: "1", Exchange: "bittrex", Pair: pair, Status: types.OrderOpen, CreatedAt: start},
+		// Same ID on another exchange is another order
+		{ID: "1", Exchange: "poloniex", Pair: pair, Status: types.OrderOpen, CreatedAt: start.Add(time.Second)},
+		{ID: "1", Exchange: "bittrex", Pair: pair, Status: types.OrderPartiallyFilled, FilledQuantity: decimal.New(1, 0), CreatedAt: start},
+		{ID: "2", Exchange: "bittrex", Pair: pair, Status: types.OrderOpen, CreatedAt: start.Add(time.Minute)},
+		{ID: "1", Exchange: "bittrex", Pair: pair, Status: types.OrderFilled, FilledQuantity: decimal.New(2, 0), CreatedAt: start},
+	}
+	for _, o := range states {
+		if err := db.SaveOrder(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string // exchange:id status, in order of first save
+	}{
+		{
+			name: "unbounded",
+			want: []string{"bittrex:1 filled", "poloniex:1 open", "bittrex:2 open"},
+		},
+		{
+			name: "from",
+			from: start.Add(time.Second),
+			want: []string{"poloniex:1 open", "bittrex:2 open"},
+		},
+		{
+			name: "to is inclusive",
+			to:   start.Add(time.Second),
+			want: []string{"bittrex:1 filled", "poloniex:1 open"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := db.Orders(tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, o := range orders {
+				got = append(got, o.Exchange+":"+o.ID+" "+string(o.Status))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("orders = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A single order is looked up by its latest state
+	order, err := db.Order("bittrex", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order == nil || order.Status != types.OrderFilled || !order.FilledQuantity.Equal(decimal.New(2, 0)) {
+		t.Errorf("order = %+v, want filled 2", order)
+	}
+	if order, err := db.Order("bittrex", "3"); order != nil || err != nil {
+		t.Errorf("unknown order = %+v (%v), want nil", order, err)
+	}
+}
+
+func TestCorruptedRecord(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Only the last record can be torn, a corrupted one before it is an error
+	records, _ := json.Marshal(&Transfer{Currency: "BTC"})
+	content := "{\n" + string(records) + "\n"
+	if err := ioutil.WriteFile(tablePath(dir, tableTransfers), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Transfers(time.Time{}, time.Time{}); err == nil || !strings.HasPrefix(err.Error(), "Corrupted transfers record") {
+		t.Errorf("error = %v, want corrupted record", err)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,80 @@
+package symbols
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Format describes how an exchange spells its market names
+type Format string
+
+const (
+	// QuoteFirst markets start with quote currency, e.g. BTC-LTC on Bittrex or BTC_LTC on Poloniex
+	QuoteFirst Format = "quote-first"
+	// BaseFirst markets start with base currency, e.g. LTC/BTC
+	BaseFirst Format = "base-first"
+	// Concatenated markets have no separator, e.g. LTCBTC, known quote currencies are used to split them
+	Concatenated Format = "concatenated"
+	// Kraken markets prefix crypto currencies with X and fiat currencies with Z, e.g. XXBTZUSD
+	Kraken Format = "kraken"
+)
+
+// ExchangeConfig describes symbols of a single exchange
+type ExchangeConfig struct {
+	Format    Format            `json:"format"`
+	Separator string            `json:"separator"`
+	Quotes    []string          `json:"quotes"`  // raw quote currency symbols, needed to split concatenated markets
+	Aliases   map[string]string `json:"aliases"` // raw currency symbol -> canonical currency symbol
+}
+
+// Config stores exchange symbol configurations keyed by exchange name
+type Config struct {
+	Exchanges map[string]*ExchangeConfig `json:"exchanges"`
+}
+
+// DefaultConfig returns configuration of exchanges we know about
+func DefaultConfig() *Config {
+	return &Config{
+		Exchanges: map[string]*ExchangeConfig{
+			"bittrex": {
+				Format:    QuoteFirst,
+				Separator: "-",
+				Aliases: map[string]string{
+					"BCC": "BCH",
+				},
+			},
+			"poloniex": {
+				Format:    QuoteFirst,
+				Separator: "_",
+				Aliases: map[string]string{
+					"STR": "XLM",
+				},
+			},
+			"binance": {
+				Format: Concatenated,
+				Quotes: []string{"BTC", "ETH", "BNB", "USDT"},
+				Aliases: map[string]string{
+					"BCC": "BCH",
+				},
+			},
+			"kraken": {
+				Format: Kraken,
+				Quotes: []string{"XBT", "ETH", "USD", "EUR", "CAD", "JPY", "GBP"},
+				Aliases: map[string]string{
+					"XBT": "BTC",
+					"XDG": "DOGE",
+				},
+			},
+		},
+	}
+}
+
+// ReadConfig decodes JSON configuration, e.g. {"exchanges": {"bittrex": {"aliases": {"BCC": "BCH"}}}}
+func ReadConfig(r io.Reader) (*Config, error) {
+	cnf := new(Config)
+	if err := json.NewDecoder(r).Decode(cnf); err != nil {
+		return nil, err
+	}
+
+	return cnf, nil
+}
@@ -0,0 +1,219 @@
+// Package symbols maps raw market and currency symbols of each exchange to canonical pairs
+package symbols
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+var (
+	// ErrUnknownExchange is returned when there is no symbol configuration for the exchange
+	ErrUnknownExchange = errors.New("Unknown exchange")
+	// ErrInvalidMarketName is returned when market name does not match the exchange format
+	ErrInvalidMarketName = errors.New("Invalid market name")
+)
+
+// Registry translates between exchange symbols and canonical currencies, safe for concurrent use
+type Registry struct {
+	exchanges map[string]*ExchangeConfig
+	reverse   map[string]map[string]string // canonical -> raw currency symbol per exchange
+	mu        sync.RWMutex
+}
+
+// New returns new Registry instance with default configuration extended by cnf, which can be nil
+func New(cnf *Config) *Registry {
+	r := &Registry{
+		exchanges: make(map[string]*ExchangeConfig),
+		reverse:   make(map[string]map[string]string),
+	}
+
+	r.Load(DefaultConfig())
+	if cnf != nil {
+		r.Load(cnf)
+	}
+
+	return r
+}
+
+// Load extends the registry, format of an already known exchange is replaced when set and aliases are merged
+func (r *Registry) Load(cnf *Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for exchange, exchangeCnf := range cnf.Exchanges {
+		current, ok := r.exchanges[exchange]
+		if !ok {
+			current = &ExchangeConfig{Aliases: make(map[string]string)}
+			r.exchanges[exchange] = current
+			r.reverse[exchange] = make(map[string]string)
+		}
+
+		if exchangeCnf.Format != "" {
+			current.Format = exchangeCnf.Format
+			current.Separator = exchangeCnf.Separator
+		}
+		if len(exchangeCnf.Quotes) > 0 {
+			current.Quotes = exchangeCnf.Quotes
+		}
+		for raw, canonical := range exchangeCnf.Aliases {
+			r.addAlias(exchange, raw, canonical)
+		}
+	}
+}
+
+// AddAlias maps a raw currency symbol of an exchange to a canonical symbol, e.g. XBT to BTC
+func (r *Registry) AddAlias(exchange, raw, canonical string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.exchanges[exchange]; !ok {
+		return ErrUnknownExchange
+	}
+
+	r.addAlias(exchange, raw, canonical)
+	return nil
+}
+
+// Currency returns canonical symbol for a raw currency symbol of an exchange
+func (r *Registry) Currency(exchange, raw string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.currency(exchange, raw)
+}
+
+// RawCurrency returns raw currency symbol of an exchange for a canonical symbol, e.g. BCC for BCH on Bittrex
+func (r *Registry) RawCurrency(exchange, canonical string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.raw(exchange, canonical)
+}
+
+// ParsePair returns canonical pair for a raw market name of an exchange
+func (r *Registry) ParsePair(exchange, marketName string) (types.Pair, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cnf, ok := r.exchanges[exchange]
+	if !ok {
+		return types.Pair{}, ErrUnknownExchange
+	}
+
+	base, quote, err := split(cnf, marketName)
+	if err != nil {
+		return types.Pair{}, fmt.Errorf("%v: %s", err, marketName)
+	}
+
+	return types.Pair{
+		Base:  r.currency(exchange, base),
+		Quote: r.currency(exchange, quote),
+	}, nil
+}
+
+// MarketName returns raw market name of an exchange for a canonical pair
+func (r *Registry) MarketName(exchange string, pair types.Pair) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cnf, ok := r.exchanges[exchange]
+	if !ok {
+		return "", ErrUnknownExchange
+	}
+
+	base, quote := r.raw(exchange, pair.Base), r.raw(exchange, pair.Quote)
+	switch cnf.Format {
+	case QuoteFirst:
+		return quote + cnf.Separator + base, nil
+	case BaseFirst:
+		return base + cnf.Separator + quote, nil
+	case Kraken:
+		// Only legacy names of two 3 character codes are prefixed, e.g. XXBTZUSD but DASHEUR
+		if len(base) == 3 && len(quote) == 3 {
+			return krakenPrefix(base) + krakenPrefix(quote), nil
+		}
+		return base + quote, nil
+	default:
+		return base + quote, nil
+	}
+}
+
+func (r *Registry) addAlias(exchange, raw, canonical string) {
+	r.exchanges[exchange].Aliases[raw] = canonical
+	r.reverse[exchange][canonical] = raw
+}
+
+func (r *Registry) currency(exchange, raw string) string {
+	if cnf, ok := r.exchanges[exchange]; ok {
+		if canonical, ok := cnf.Aliases[raw]; ok {
+			return canonical
+		}
+	}
+
+	return raw
+}
+
+func (r *Registry) raw(exchange, canonical string) string {
+	if raw, ok := r.reverse[exchange][canonical]; ok {
+		return raw
+	}
+
+	return canonical
+}
+
+// split returns raw base and quote currency symbols of a market name
+func split(cnf *ExchangeConfig, marketName string) (string, string, error) {
+	switch cnf.Format {
+	case QuoteFirst, BaseFirst:
+		parts := strings.Split(marketName, cnf.Separator)
+		if cnf.Separator == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return "", "", ErrInvalidMarketName
+		}
+		if cnf.Format == QuoteFirst {
+			return parts[1], parts[0], nil
+		}
+		return parts[0], parts[1], nil
+	case Kraken:
+		// Legacy 8 character names such as XXBTZUSD or XLTCXXBT
+		if len(marketName) == 8 && isKrakenPrefix(marketName[0]) && isKrakenPrefix(marketName[4]) {
+			return marketName[1:4], marketName[5:], nil
+		}
+		return splitConcatenated(cnf.Quotes, marketName)
+	default:
+		return splitConcatenated(cnf.Quotes, marketName)
+	}
+}
+
+// splitConcatenated uses the longest known quote currency the market name ends with
+func splitConcatenated(quotes []string, marketName string) (string, string, error) {
+	var quote string
+	for _, q := range quotes {
+		if len(q) > len(quote) && len(q) < len(marketName) && strings.HasSuffix(marketName, q) {
+			quote = q
+		}
+	}
+
+	if quote == "" {
+		return "", "", ErrInvalidMarketName
+	}
+
+	return strings.TrimSuffix(marketName, quote), quote, nil
+}
+
+func isKrakenPrefix(c byte) bool {
+	return c == 'X' || c == 'Z'
+}
+
+// krakenPrefix builds legacy Kraken currency code, fiat currencies are prefixed with Z
+func krakenPrefix(currency string) string {
+	switch currency {
+	case "USD", "EUR", "CAD", "JPY", "GBP":
+		return "Z" + currency
+	default:
+		return "X" + currency
+	}
+}
@@ -0,0 +1,128 @@
+package symbols
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/arbitrage/types"
+)
+
+func TestParsePair(t *testing.T) {
+	tests := []struct {
+		exchange   string
+		marketName string
+		pair       types.Pair
+		err        error
+	}{
+		{"bittrex", "BTC-LTC", types.Pair{Base: "LTC", Quote: "BTC"}, nil},
+		{"bittrex", "BTC-BCC", types.Pair{Base: "BCH", Quote: "BTC"}, nil},
+		{"bittrex", "BTCLTC", types.Pair{}, ErrInvalidMarketName},
+		{"bittrex", "BTC-", types.Pair{}, ErrInvalidMarketName},
+		{"bittrex", "BTC-LTC-ETH", types.Pair{}, ErrInvalidMarketName},
+		{"poloniex", "BTC_STR", types.Pair{Base: "XLM", Quote: "BTC"}, nil},
+		{"poloniex", "BTC-LTC", types.Pair{}, ErrInvalidMarketName},
+		{"binance", "ETHBTC", types.Pair{Base: "ETH", Quote: "BTC"}, nil},
+		{"binance", "BNBUSDT", types.Pair{Base: "BNB", Quote: "USDT"}, nil},
+		{"binance", "BCCBTC", types.Pair{Base: "BCH", Quote: "BTC"}, nil},
+		{"binance", "USDT", types.Pair{}, ErrInvalidMarketName},
+		{"binance", "LTCXRP", types.Pair{}, ErrInvalidMarketName},
+		{"kraken", "XXBTZUSD", types.Pair{Base: "BTC", Quote: "USD"}, nil},
+		{"kraken", "XLTCXXBT", types.Pair{Base: "LTC", Quote: "BTC"}, nil},
+		{"kraken", "XDGUSD", types.Pair{Base: "DOGE", Quote: "USD"}, nil},
+		{"kraken", "DASHEUR", types.Pair{Base: "DASH", Quote: "EUR"}, nil},
+		{"unknown", "BTC-LTC", types.Pair{}, ErrUnknownExchange},
+	}
+
+	r := New(nil)
+	for _, tt := range tests {
+		pair, err := r.ParsePair(tt.exchange, tt.marketName)
+		if tt.err != nil {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err.Error()) {
+				t.Errorf("%s %s error = %v, want %v", tt.exchange, tt.marketName, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s unexpected error: %v", tt.exchange, tt.marketName, err)
+			continue
+		}
+		if pair != tt.pair {
+			t.Errorf("%s %s = %s, want %s", tt.exchange, tt.marketName, pair, tt.pair)
+		}
+	}
+}
+
+func TestMarketName(t *testing.T) {
+	tests := []struct {
+		exchange   string
+		pair       types.Pair
+		marketName string
+	}{
+		{"bittrex", types.Pair{Base: "LTC", Quote: "BTC"}, "BTC-LTC"},
+		{"bittrex", types.Pair{Base: "BCH", Quote: "BTC"}, "BTC-BCC"},
+		{"poloniex", types.Pair{Base: "XLM", Quote: "BTC"}, "BTC_STR"},
+		{"binance", types.Pair{Base: "BNB", Quote: "USDT"}, "BNBUSDT"},
+		{"kraken", types.Pair{Base: "BTC", Quote: "USD"}, "XXBTZUSD"},
+		{"kraken", types.Pair{Base: "DOGE", Quote: "USD"}, "XXDGZUSD"},
+		{"kraken", types.Pair{Base: "DASH", Quote: "EUR"}, "DASHEUR"},
+	}
+
+	r := New(nil)
+	for _, tt := range tests {
+		marketName, err := r.MarketName(tt.exchange, tt.pair)
+		if err != nil {
+			t.Errorf("%s %s unexpected error: %v", tt.exchange, tt.pair, err)
+			continue
+		}
+		if marketName != tt.marketName {
+			t.Errorf("%s %s = %s, want %s", tt.exchange, tt.pair, marketName, tt.marketName)
+		}
+
+		// Market names round trip to the same pair
+		pair, err := r.ParsePair(tt.exchange, marketName)
+		if err != nil || pair != tt.pair {
+			t.Errorf("%s %s parsed as %s (%v), want %s", tt.exchange, marketName, pair, err, tt.pair)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cnf, err := ReadConfig(strings.NewReader(`{"exchanges": {
+		"bittrex": {"aliases": {"NEO": "ANS"}},
+		"hitbtc": {"format": "concatenated", "quotes": ["BTC", "USD"], "aliases": {"XDG": "DOGE"}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(cnf)
+
+	tests := []struct {
+		exchange   string
+		marketName string
+		pair       types.Pair
+	}{
+		// Aliases are merged into defaults and the format is kept
+		{"bittrex", "BTC-NEO", types.Pair{Base: "ANS", Quote: "BTC"}},
+		{"bittrex", "BTC-BCC", types.Pair{Base: "BCH", Quote: "BTC"}},
+		{"hitbtc", "XDGBTC", types.Pair{Base: "DOGE", Quote: "BTC"}},
+	}
+	for _, tt := range tests {
+		pair, err := r.ParsePair(tt.exchange, tt.marketName)
+		if err != nil || pair != tt.pair {
+			t.Errorf("%s %s = %s (%v), want %s", tt.exchange, tt.marketName, pair, err, tt.pair)
+		}
+	}
+
+	if err := r.AddAlias("unknown", "XBT", "BTC"); err != ErrUnknownExchange {
+		t.Errorf("error = %v, want %v", err, ErrUnknownExchange)
+	}
+	if err := r.AddAlias("binance", "YOYO", "YOYOW"); err != nil {
+		t.Fatal(err)
+	}
+	if currency := r.Currency("binance", "YOYO"); currency != "YOYOW" {
+		t.Errorf("currency = %s, want YOYOW", currency)
+	}
+	if raw := r.RawCurrency("binance", "YOYOW"); raw != "YOYO" {
+		t.Errorf("raw currency = %s, want YOYO", raw)
+	}
+}
@@ -1,21 +1,219 @@
 package types
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+// Pair identifies a traded instrument, e.g. LTC/BTC means price of LTC in BTC
+type Pair struct {
+	Base  string
+	Quote string
+}
+
+// String returns BASE/QUOTE representation of the pair
+func (p Pair) String() string {
+	return p.Base + "/" + p.Quote
+}
+
+// Side is either buy or sell, always from the base currency point of view
+type Side string
+
+const (
+	// Buy means giving quote currency to get base currency
+	Buy Side = "buy"
+	// Sell means giving base currency to get quote currency
+	Sell Side = "sell"
+)
+
 // Ticker ...
 type Ticker struct {
-	Bid  decimal.Decimal
-	Ask  decimal.Decimal
-	Last decimal.Decimal
-	Time time.Time
+	Exchange string // unique exchange name as returned by GetName
+	Market   string // raw market symbol as used by the exchange, e.g. BTC-LTC
+	Pair     Pair
+	Bid      decimal.Decimal
+	Ask      decimal.Decimal
+	Last     decimal.Decimal
+	// Time is when the exchange quoted the prices, the receive time when the exchange does not say
+	Time       time.Time
+	ReceivedAt time.Time // local time the ticker was received from the exchange
+}
+
+// PriceLevel is aggregated quantity available at a price
+type PriceLevel struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+// OrderBook is a snapshot of order book depth
+type OrderBook struct {
+	Exchange string
+	Market   string
+	Pair     Pair
+	Bids     []*PriceLevel // best (highest) bid first
+	Asks     []*PriceLevel // best (lowest) ask first
+	Time     time.Time
+}
+
+// Balance of a single currency held on an exchange
+type Balance struct {
+	Currency  string
+	Available decimal.Decimal // can be spent right now
+	Pending   decimal.Decimal // e.g. deposits waiting for confirmations
+	Total     decimal.Decimal // includes funds reserved by open orders
+}
+
+// OrderStatus ...
+type OrderStatus string
+
+const (
+	// OrderOpen means the order has been accepted by the exchange and nothing has been filled yet
+	OrderOpen OrderStatus = "open"
+	// OrderPartiallyFilled means the order is still open but some quantity has been filled
+	OrderPartiallyFilled OrderStatus = "partially_filled"
+	// OrderFilled means the whole quantity has been filled
+	OrderFilled OrderStatus = "filled"
+	// OrderCancelled means the order was closed before being fully filled
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// Order is a limit order placed on an exchange
+type Order struct {
+	ID             string
+	Exchange       string
+	Pair           Pair
+	Side           Side
+	Price          decimal.Decimal // limit price
+	Quantity       decimal.Decimal
+	FilledQuantity decimal.Decimal
+	AveragePrice   decimal.Decimal // average price of the filled quantity
+	Fee            decimal.Decimal // commission paid in quote currency
+	Status         OrderStatus
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// IsClosed returns true when the order cannot be filled any further
+func (o *Order) IsClosed() bool {
+	return o.Status == OrderFilled || o.Status == OrderCancelled
+}
+
+// Currency describes how a currency can be transferred from an exchange
+type Currency struct {
+	Currency         string
+	WithdrawalFee    decimal.Decimal // fixed fee in units of the currency
+	MinConfirmations int             // before a deposit is credited
+	Active           bool            // deposits and withdrawals are enabled
+}
+
+// TradingRules restrict orders an exchange accepts for a pair, zero limits and steps mean no
+// restriction but every order is rejected unless the pair is Active
+type TradingRules struct {
+	Exchange     string
+	Pair         Pair
+	MinQuantity  decimal.Decimal
+	MinNotional  decimal.Decimal // minimum price times quantity in quote currency
+	PriceTick    decimal.Decimal
+	QuantityStep decimal.Decimal
+	Active       bool
+}
+
+// RoundPrice rounds the price to the tick so it is never worse for us, down when buying and up when selling
+func (r *TradingRules) RoundPrice(price decimal.Decimal, side Side) decimal.Decimal {
+	if r.PriceTick.Sign() <= 0 {
+		return price
+	}
+
+	ticks := price.Div(r.PriceTick)
+	if side == Buy {
+		ticks = ticks.Floor()
+	} else {
+		ticks = ticks.Ceil()
+	}
+
+	return ticks.Mul(r.PriceTick)
+}
+
+// RoundQuantity rounds the quantity down to the step
+func (r *TradingRules) RoundQuantity(quantity decimal.Decimal) decimal.Decimal {
+	if r.QuantityStep.Sign() <= 0 {
+		return quantity
+	}
+
+	return quantity.Div(r.QuantityStep).Floor().Mul(r.QuantityStep)
+}
+
+// Validate returns an error describing why the exchange would reject the order
+func (r *TradingRules) Validate(price, quantity decimal.Decimal) error {
+	if !r.Active {
+		return fmt.Errorf("%s %s is not active", r.Exchange, r.Pair)
+	}
+
+	if quantity.LessThan(r.MinQuantity) {
+		return fmt.Errorf("%s %s quantity %s below minimum %s", r.Exchange, r.Pair, quantity, r.MinQuantity)
+	}
+
+	if notional := price.Mul(quantity); notional.LessThan(r.MinNotional) {
+		return fmt.Errorf("%s %s notional %s below minimum %s", r.Exchange, r.Pair, notional, r.MinNotional)
+	}
+
+	return nil
 }
 
 // Exchange ...
 type Exchange interface {
+	GetName() string
 	Run(tickers chan *Ticker) error
 	Quit() error
 }
+
+// OrderBookStreamer is an optional capability of exchanges able to stream
+// order book snapshots alongside tickers, it should stop on Quit
+type OrderBookStreamer interface {
+	RunOrderBooks(books chan *OrderBook) error
+}
+
+// BalanceProvider is an optional capability of exchanges able to report our balances
+type BalanceProvider interface {
+	FetchBalances() ([]*Balance, error)
+}
+
+// Authenticator is an optional capability of exchanges whose private endpoints
+// need API credentials, it reports whether they were configured
+type Authenticator interface {
+	HasCredentials() bool
+}
+
+// Trader is an optional capability of exchanges we can place orders on,
+// exchanges not implementing it are used read-only
+type Trader interface {
+	PlaceOrder(pair Pair, side Side, price, quantity decimal.Decimal) (*Order, error)
+	CancelOrder(id string) error
+	FetchOrder(id string) (*Order, error)
+	FetchOpenOrders(pair Pair) ([]*Order, error) // all pairs when pair is empty
+}
+
+// CurrencyProvider is an optional capability of exchanges able to describe their currencies
+type CurrencyProvider interface {
+	FetchCurrencies() ([]*Currency, error)
+}
+
+// DepositAddress is where deposits of a currency are sent to, memo based assets, e.g. XRP,
+// share one address and credit deposits by the memo (payment ID or destination tag)
+type DepositAddress struct {
+	Address string
+	Memo    string // empty unless the asset requires it
+}
+
+// Withdrawer is an optional capability of exchanges we can move funds out of and into
+type Withdrawer interface {
+	RequestWithdrawal(currency string, quantity decimal.Decimal, address *DepositAddress) (string, error)
+	FetchDepositAddress(currency string) (*DepositAddress, error)
+}
+
+// RulesProvider is an optional capability of exchanges able to describe their trading rules
+type RulesProvider interface {
+	FetchTradingRules() ([]*TradingRules, error)
+}
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundPrice(t *testing.T) {
+	tests := []struct {
+		tick  string
+		price string
+		side  Side
+		want  string
+	}{
+		{"0", "0.012345678", Buy, "0.012345678"},
+		{"0.00000001", "0.012345678", Buy, "0.01234567"},
+		{"0.00000001", "0.012345678", Sell, "0.01234568"},
+		{"0.00000001", "0.01234567", Buy, "0.01234567"},
+		{"0.00000001", "0.01234567", Sell, "0.01234567"},
+		{"0.5", "10.7", Buy, "10.5"},
+		{"0.5", "10.7", Sell, "11"},
+	}
+
+	for _, tt := range tests {
+		r := &TradingRules{PriceTick: mustDecimal(tt.tick)}
+		if got := r.RoundPrice(mustDecimal(tt.price), tt.side); !got.Equal(mustDecimal(tt.want)) {
+			t.Errorf("%s %s with tick %s = %s, want %s", tt.side, tt.price, tt.tick, got, tt.want)
+		}
+	}
+}
+
+func TestRoundQuantity(t *testing.T) {
+	tests := []struct {
+		step     string
+		quantity string
+		want     string
+	}{
+		{"0", "1.23456789", "1.23456789"},
+		{"0.01", "1.23456789", "1.23"},
+		{"0.01", "1.23", "1.23"},
+		{"0.01", "0.009", "0"},
+		{"5", "12", "10"},
+	}
+
+	for _, tt := range tests {
+		r := &TradingRules{QuantityStep: mustDecimal(tt.step)}
+		if got := r.RoundQuantity(mustDecimal(tt.quantity)); !got.Equal(mustDecimal(tt.want)) {
+			t.Errorf("%s with step %s = %s, want %s", tt.quantity, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    *TradingRules
+		price    string
+		quantity string
+		err      string // contained in the error, none when empty
+	}{
+		{
+			name:     "zero value",
+			rules:    new(TradingRules),
+			price:    "1",
+			quantity: "1",
+			err:      "not active",
+		},
+		{
+			name:     "no limits",
+			rules:    &TradingRules{Active: true},
+			price:    "0.00000001",
+			quantity: "0.00000001",
+		},
+		{
+			name:     "quantity at minimum",
+			rules:    &TradingRules{MinQuantity: mustDecimal("0.1"), Active: true},
+			price:    "1",
+			quantity: "0.1",
+		},
+		{
+			name:     "quantity below minimum",
+			rules:    &TradingRules{MinQuantity: mustDecimal("0.1"), Active: true},
+			price:    "1",
+			quantity: "0.09",
+			err:      "quantity 0.09 below minimum 0.1",
+		},
+		{
+			name:     "notional at minimum",
+			rules:    &TradingRules{MinNotional: mustDecimal("0.0005"), Active: true},
+			price:    "0.01",
+			quantity: "0.05",
+		},
+		{
+			name:     "notional below minimum",
+			rules:    &TradingRules{MinNotional: mustDecimal("0.0005"), Active: true},
+			price:    "0.01",
+			quantity: "0.049",
+			err:      "notional 0.00049 below minimum 0.0005",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate(mustDecimal(tt.price), mustDecimal(tt.quantity))
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func mustDecimal(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}